@@ -1,9 +1,9 @@
 package args
 
 import (
+	"errors"
 	"fmt"
 	"io"
-	"os"
 	"reflect"
 	"strconv"
 	"strings"
@@ -54,9 +54,136 @@ The main use case is struct. Example:
 $ ./main --foo 5 --bar 3.5 --baz asdf
 
 {Foo:5 Bar:3.5 Baz:asdf}
+
+The args tag can also be written as space separated keys, which allows
+commas in descriptions. Keys that take a value can be given as separate
+tags. These fields are tagged the same way:
+
+	Size int `args:"size\\, in bytes,-s,r"`
+	Size int `args:"help='size, in bytes' short=s required"`
+	Size int `help:"size, in bytes" short:"s" args:",r"`
+
+Long flags are named after their fields, in kebab-case, so a field called
+MaxConns is set with --max-conns. The tag can name the long flag instead,
+and give it aliases: a field tagged `args:"connection limit,--max,--limit"`
+is set with --max or --limit.
+
+Embedded structs are flattened, so their fields are flags of the outer
+struct. Other struct fields are groups, whose flags are prefixed with the
+name of the group: the Host field of a field called DB is set with
+--db-host. The prefix tag key picks another prefix, and the help key sets
+the heading of the group in Usage. A pointer to a struct is only a group if
+it is embedded or tagged with one of these keys, and is skipped otherwise:
+
+	type Args struct {
+		LogOptions
+		DB    DBOptions `args:"help='Database options'"`
+		Cache DBOptions `args:"prefix=redis"`
+	}
+
+Flags can also be set with environment variables, which are used when the
+flag isn't given. The env tag key names the variable, as in env=SIZE. See
+Parser.EnvPrefix for naming variables after their flags.
+
+Settings can also come from JSON or INI config files, which are used when
+neither the flag nor its variable is set. A string flag tagged with the
+config key, as in `args:"config file,config"`, names a config file, and so
+does the built-in flag that Parser.ConfigFlag names. See
+Parser.ConfigFiles. ParseResult reports where the value of each field came
+from.
+
+Fields can be strings, booleans, numbers, slices of those, or pointers to
+them. Types that implement Unmarshaler, flag.Value or
+encoding.TextUnmarshaler parse their own values, and Usage shows their
+defaults with their String or MarshalText methods.
+
+A time.Duration is parsed with time.ParseDuration, as in 1m30s. A time.Time
+is in RFC 3339 or a date such as 2006-01-02, unless the layout key names
+other layouts, as in layout=Kitchen or layout='15:04'. It can also be
+relative to Parser.Now, as in -2h, +30m, now, today, yesterday or tomorrow.
+
+Network addresses can be net.IP, net.IPNet, netip.Addr, netip.Prefix,
+netip.AddrPort, url.URL or HostPort. The family key restricts addresses
+to ipv4 or ipv6, the scheme key restricts URLs to its schemes, and the port
+key gives the port of hosts that are given without one:
+
+	Listen   HostPort `args:"help='listen address' port=8080"`
+	Upstream *url.URL `args:"help='upstream' scheme=http scheme=https"`
+
+A ByteSize takes SI and IEC suffixes, as in 64k or 512MiB, and a Percent
+takes percentages, as in 75%. The si key lets other integers take SI
+suffixes, as in 10k.
+
+The choices key limits a field to a list of values, as in
+choices=json|yaml|text, and so does a type that implements Enum. With the
+ignorecase key or Parser.IgnoreCase, the values match regardless of case.
+
+Values that are given can be validated with the min, max, len, pattern,
+nonempty, existing-file and existing-dir keys, as in `args:"a port,min=1"`.
+The rules apply to each element of a slice. Parse returns every violation
+at once, in a ValidationError, along with the values that don't convert to
+their fields and the required arguments that are missing.
+
+Flags that name the same set with the xor key can't be given together.
+With the oneof key, exactly one of the set must be given, and with the anyof
+key, at least one. The requires key names a flag that must be given with
+this one. Usage shows the sets in the synopsis, as [--quiet | --loud],
+(--file | --url) and (--user | --group)... respectively. Within an xor or
+oneof set, a flag on the command line replaces the others of the set that
+come from the environment or config files, and the environment replaces
+config files:
+
+	File string `args:"help='read a file' oneof=source"`
+	URL  string `args:"help='read a url' oneof=source"`
+
+The default key gives a field a default, which is used when neither the
+arguments, the environment nor a config file set it, and the field still
+holds its zero value, so a value the caller put in the struct beforehand
+is kept. It suits any type, pointers included, and the defaults of slices
+are separated by commas, as in `args:"help=tags default='a,b'"`. A field
+with a default is never required.
+
+A boolean flag is switched on by giving it, and off by giving it with --no-
+in front, as in --no-color, unless its tag has the nonegate key. It can
+also be given a value, as in --color=false; true, false, yes, no, 1 and 0
+are accepted. The last of these wins, so --no-color can override --color.
+A *bool stays nil unless the flag is given, so it tells apart unset, true
+and false.
+
+An integer flag with the count key counts how many times it is given, even
+within a cluster, so -vvv sets it to 3. A value sets the count outright, as
+in --verbose=3. With a value, as in count=3, the key caps the count:
+
+	Verbose int `args:"help='more output' short=v count=3"`
+
+A flag with the implicit key takes an optional value. Given on its own, it
+takes the implicit value, and a value must be attached to it with =, so
+that it doesn't take the positional argument that follows:
+
+	Color string `args:"help='when to use color' choices=auto|always|never default=auto implicit=always"`
+
+Usage shows such a flag as --color[=COLOR].
+
+Values can also be attached to their flags, as in --baz=asdf or -f5. This is
+the safest way to pass a value that begins with a dash.
+
+Flags that don't match any field are an error, unless Parser.AllowUnknown
+is set.
+
+Positional arguments can be bound to fields too. See Positionals. For
+programs with commands, see Run.
+
+Parse writes nothing itself. Unless strukt declares them, -h and --help are
+unknown flags, like any other; set Parser.Help to have them write the usage
+instead, as MustParse does.
 */
 func Parse(strukt interface{}) error {
-	return parse(strukt, os.Args[1:])
+	return new(Parser).Parse(strukt)
+}
+
+// ParseArgs is like Parse, but parses args instead of os.Args[1:].
+func ParseArgs(strukt interface{}, args []string) error {
+	return new(Parser).ParseArgs(strukt, args)
 }
 
 /*Usage writes the usage for a user program to w.
@@ -83,40 +210,183 @@ if not supplied.
 A non-pointer struct value will always have a default. If not specified,
 it will be the zero value for the type.
 
-Pointer values cannot have defaults.
+Pointer values have no default, unless their tag gives one with the default
+key, as in `args:"a ratio,default=0.5"`. A default in the tag is shown
+for fields of any type whose value in strukt is the zero value.
+
+Boolean flags that can be negated are shown as --[no-]color.
 */
 func Usage(w io.Writer, strukt interface{}) error {
+	return new(Parser).usage(w, strukt)
+}
+
+func (p *Parser) usage(w io.Writer, strukt interface{}) error {
 	val := reflect.ValueOf(strukt)
 	typ := val.Type()
 	if typ.Kind() != reflect.Struct {
 		return fmt.Errorf(
 			"can only print usage with struct, not %s", typ.Kind().String())
 	}
-	if _, err := fmt.Fprint(w, "usage:\n"); err != nil {
+	// Work on an addressable copy, so the spec can be built the same way
+	// it is for Parse.
+	cp := reflect.New(typ).Elem()
+	cp.Set(val)
+	s, err := newSpec(cp, p)
+	if err != nil {
 		return err
 	}
-	for i := 0; i < typ.NumField(); i++ {
-		ftype := typ.Field(i)
-		fval := val.Field(i)
-		if ftype.PkgPath == "" {
-			if err := usageForField(w, ftype, fval); err != nil {
+	if err := s.addConfigFlag(); err != nil {
+		return err
+	}
+	return s.writeUsage(w)
+}
+
+/*
+Positionals holds positional arguments that are not bound to a field.
+
+Fields tagged with "pos" receive positional arguments in the order they are
+declared. A trailing slice field receives all the remaining arguments.
+Non-pointer, non-slice positional fields are required.
+
+	type Args struct {
+		Verbose bool     `args:"be chatty,-v"`
+		Src     string   `args:"file to copy,pos"`
+		Dst     string   `args:"destination,pos"`
+		Extra   []string `args:"more files,pos"`
+	}
+
+Embed Positionals in the args struct to accept positional arguments that
+are not bound to a typed field. Without it, an unexpected positional
+argument is an error.
+
+	type Args struct {
+		args.Positionals
+		Verbose bool `args:"be chatty,-v"`
+	}
+
+Positional arguments may appear before, between or after flags. Everything
+after a "--" argument is positional, even if it begins with a dash.
+
+To pass arguments through to another program, tag a []string field with
+"rest". It receives everything after "--" verbatim, and those arguments are
+no longer treated as positional.
+
+	type Args struct {
+		Host string   `args:"host to connect to,pos"`
+		Rest []string `args:"ssh options,rest"`
+	}
+*/
+type Positionals struct {
+	data []string
+}
+
+// Args returns the positional arguments, in the order they were given.
+func (p Positionals) Args() []string {
+	return p.data
+}
+
+// Arg returns the i'th positional argument. It returns an empty string if
+// the argument does not exist.
+func (p Positionals) Arg(i int) string {
+	if i < 0 || i >= len(p.data) {
+		return ""
+	}
+	return p.data[i]
+}
+
+// NArg returns the number of positional arguments.
+func (p Positionals) NArg() int {
+	return len(p.data)
+}
+
+// writeUsage writes the usage for the struct that s describes to w.
+func (s *spec) writeUsage(w io.Writer) error {
+	if _, err := fmt.Fprintf(w, "usage: %s\n", s.synopsis()); err != nil {
+		return err
+	}
+	for _, opt := range s.flags {
+		if opt.group != nil {
+			continue
+		}
+		if err := usageForField(w, opt); err != nil {
+			return err
+		}
+	}
+	positionals := append([]*option{}, s.positionals...)
+	if s.rest != nil {
+		positionals = append(positionals, s.rest)
+	}
+	for _, opt := range positionals {
+		if _, err := fmt.Fprintf(w, " \t%s\t\t%s\n", opt.placeholder(), opt.usageText()); err != nil {
+			return err
+		}
+	}
+	for _, g := range s.groups {
+		if _, err := fmt.Fprintf(w, "\n%s:\n", g.heading); err != nil {
+			return err
+		}
+		for _, opt := range s.flags {
+			if opt.group != g {
+				continue
+			}
+			if err := usageForField(w, opt); err != nil {
 				return err
 			}
 		}
 	}
 
+	if len(s.commands) > 0 {
+		if _, err := fmt.Fprint(w, "\ncommands:\n"); err != nil {
+			return err
+		}
+		for _, cmd := range s.commands {
+			if _, err := fmt.Fprintf(w, " \t%s\t\t%s\n", cmd.name, cmd.help); err != nil {
+				return err
+			}
+		}
+	}
+	if s.parent != nil {
+		if _, err := fmt.Fprint(w, "\nglobal options:\n"); err != nil {
+			return err
+		}
+		for parent := s.parent; parent != nil; parent = parent.parent {
+			for _, opt := range parent.flags {
+				if err := usageForField(w, opt); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
 	return nil
 }
 
-// Embed Positionals in your args struct to specify positional arguments.
-// TODO
-type Positionals struct {
-	data []string
+// usageText returns the description of opt in Usage, with its choices, its
+// implicit value, the flags it requires, and its environment variable.
+func (opt *option) usageText() string {
+	desc := opt.tag.Description
+	if choices := opt.choices(); len(choices) > 0 {
+		desc += " {" + strings.Join(choices, "|") + "}"
+	}
+	if opt.tag.Implicit != nil {
+		desc += fmt.Sprintf(" (implicit: %s)", *opt.tag.Implicit)
+	}
+	if len(opt.requires) > 0 {
+		names := make([]string, len(opt.requires))
+		for i, other := range opt.requires {
+			names[i] = "--" + other.name
+		}
+		desc += " (requires " + strings.Join(names, ", ") + ")"
+	}
+	if opt.env != "" {
+		desc += " [$" + opt.env + "]"
+	}
+	return desc
 }
 
-// usageForField writes the usage for a single argument from a struct field
-func usageForField(w io.Writer, field reflect.StructField, fieldVal reflect.Value) error {
-	td := parseTagData(field.Tag)
+// usageForField writes the usage for a single flag from a struct field
+func usageForField(w io.Writer, opt *option) error {
+	td := opt.tag
 	if td.ShortFlag != "" {
 		if _, err := fmt.Fprintf(w, " -%s,\t", td.ShortFlag); err != nil {
 			return err
@@ -126,22 +396,29 @@ func usageForField(w io.Writer, field reflect.StructField, fieldVal reflect.Valu
 			return err
 		}
 	}
-	if fieldVal.Kind() != reflect.Ptr {
-		var defaultValue string
-		if fieldVal.Kind() == reflect.String {
-			defaultValue = fmt.Sprintf("%q", fieldVal.Interface())
-		} else {
-			defaultValue = fmt.Sprintf("%v", fieldVal.Interface())
+	names := "--" + strings.Join(opt.longs, ", --")
+	if opt.negatable() {
+		names = "--[no-]" + strings.Join(opt.longs, ", --[no-]")
+	} else if opt.tag.Implicit != nil {
+		names += "[=" + strings.ToUpper(opt.field.Name) + "]"
+	}
+	desc := opt.usageText()
+	fieldVal := opt.value
+	if opt.tag.Default != nil && fieldVal.IsZero() {
+		def := *opt.tag.Default
+		if kindOf(opt.field.Type) == reflect.String && !isValue(opt.field.Type) {
+			def = fmt.Sprintf("%q", def)
 		}
+		_, err := fmt.Fprintf(w, "%s\t(default: %s)\t%s\n", names, def, desc)
+		return err
+	} else if fieldVal.Kind() != reflect.Ptr {
 		_, err := fmt.Fprintf(
-			w, "--%s\t(default: %s)\t%s\n", strings.ToLower(field.Name),
-			defaultValue, td.Description)
+			w, "%s\t(default: %s)\t%s\n", names, formatValue(fieldVal), desc)
 		if err != nil {
 			return err
 		}
 	} else {
-		_, err := fmt.Fprintf(
-			w, "--%s\t\t%s\n", strings.ToLower(field.Name), td.Description)
+		_, err := fmt.Fprintf(w, "%s\t\t%s\n", names, desc)
 
 		if err != nil {
 			return err
@@ -152,6 +429,10 @@ func usageForField(w io.Writer, field reflect.StructField, fieldVal reflect.Valu
 
 // implementation of Parse
 func parse(data interface{}, args []string) error {
+	return new(Parser).parse(data, args)
+}
+
+func (p *Parser) parse(data interface{}, args []string) error {
 	typ, err := getType(data)
 	if err != nil {
 		return err
@@ -159,9 +440,10 @@ func parse(data interface{}, args []string) error {
 	v := reflect.ValueOf(data).Elem()
 	switch typ.Kind() {
 	case reflect.Struct:
-		return parseStruct(v, args)
+		_, err := p.parseStruct(v, args, new(Result))
+		return err
 	case reflect.Slice:
-		return fillSlice(v, args)
+		return fillSlice(v, "argument", args)
 	case reflect.Map:
 		return parseMap(v, args)
 	default: // should never be reached
@@ -179,126 +461,357 @@ func checkArgLen(data []string, name string) error {
 	return nil
 }
 
-type tagData struct {
-	Description string
-	Required    bool
-	ShortFlag   string
+// parseStruct walks the struct fields of v, and tries to assign items
+// from args to them. It returns the spec of the command that args select,
+// or of v if there are no commands.
+func (p *Parser) parseStruct(v reflect.Value, args []string, res *Result) (*spec, error) {
+	s, err := newSpec(v, p)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.addConfigFlag(); err != nil {
+		return nil, err
+	}
+	st := &parseState{
+		values: make(map[*option][]string),
+		index:  make(map[*option]int),
+		failed:   make(map[*option]bool),
+		shadowed: make(map[*option]bool),
+		result:   res,
+	}
+	levels, err := s.lexLevels(args, 0, st)
+	if err != nil {
+		return nil, err
+	}
+	if st.config, err = p.loadConfig(levels, st.values); err != nil {
+		return nil, err
+	}
+	p.shadow(levels, st)
+	var violations []error
+	for _, l := range levels {
+		violations = append(violations, l.apply(st)...)
+	}
+	// Options whose values couldn't be assigned were still given, as far
+	// as the constraints are concerned, but there's nothing to validate.
+	set := make(map[*option]bool)
+	for opt := range st.failed {
+		set[opt] = true
+	}
+	for _, opt := range st.set {
+		set[opt] = true
+		violations = append(violations, p.validate(opt)...)
+	}
+	for _, l := range levels {
+		violations = append(violations, l.spec.checkConstraints(set)...)
+	}
+	if len(violations) > 0 {
+		return nil, &ValidationError{Violations: violations}
+	}
+	return levels[len(levels)-1].spec, nil
+}
+
+// parseState holds what has been found for the options of a struct and
+// its commands.
+type parseState struct {
+	values map[*option][]string // the values given in the arguments
+	index  map[*option]int      // the index of the last argument of each option
+	config map[*option]*configValue
+	result *Result
+	set    []*option        // the options that were set, in order
+	failed map[*option]bool // the options whose values couldn't be assigned
+
+	// shadowed are the options that are left unset, because a higher
+	// source gave another flag of their xor or oneof set.
+	shadowed map[*option]bool
 }
 
-func parseTagData(tag reflect.StructTag) tagData {
-	td := tagData{}
-	parts := strings.Split(tag.Get("args"), ",")
-	if len(parts) > 0 {
-		td.Description = parts[0]
-	}
-	if len(parts) > 1 {
-		for i := 1; i < len(parts); i++ {
-			if strings.HasPrefix(parts[i], "-") && len(parts[i]) == 2 {
-				td.ShortFlag = parts[i][1:]
-			} else if parts[i] == "r" {
-				td.Required = true
-			}
-		}
+// record adds the setting of opt, which belongs to s, to the result.
+func (st *parseState) record(s *spec, opt *option, set Setting) {
+	set.Field = s.fieldPath + opt.path
+	set.Command = strings.Join(s.path, " ")
+	if opt.tag.Positional || opt.tag.Rest {
+		set.Flag = opt.placeholder()
+	} else {
+		set.Flag = "--" + opt.name
+	}
+	if v := reflect.Indirect(opt.value); v.IsValid() {
+		set.Value = v.Interface()
+	}
+	st.result.Settings = append(st.result.Settings, set)
+	if set.Source != SourceDefault {
+		st.set = append(st.set, opt)
 	}
-	return td
 }
 
-// parseStruct walks the struct fields of v, and tries to assign items
-// from args to them.
-func parseStruct(v reflect.Value, args []string) error {
-	rawData, err := rawArgsMap(args)
+// level is the part of the arguments that belongs to one struct: either
+// the struct given to Parse, or one of its commands.
+type level struct {
+	spec        *spec
+	positionals []string
+	posIndex    []int
+	rest        []string
+	restIndex   int
+}
+
+// lexLevels lexes args for s and for the commands that they select. The
+// values of flags are added to st, under the option they belong to. offset
+// is the index of args in the arguments given to Parse.
+func (s *spec) lexLevels(args []string, offset int, st *parseState) ([]*level, error) {
+	l, err := lexArgs(args, s)
+	if err == ErrHelp {
+		if err := s.writeUsage(s.parser.stdout()); err != nil {
+			return nil, err
+		}
+		return nil, ErrHelp
+	}
 	if err != nil {
-		return err
+		return nil, err
 	}
-	typ := v.Type()
-	for i := 0; i < v.NumField(); i++ {
-		field := typ.Field(i)
-		if field.PkgPath != "" || field.Anonymous {
-			// Non-empty PkgPath implies unexported field
-			continue
+	for key, vals := range l.flags {
+		// With AllowUnknown, unknown flags are lexed but ignored.
+		if opt := s.lookupLong(key); opt != nil {
+			if st.values[opt] == nil {
+				st.values[opt] = []string{}
+			}
+			st.values[opt] = append(st.values[opt], vals...)
+			st.index[opt] = offset + l.index[key]
 		}
-		name := strings.ToLower(field.Name)
-		ftype := field.Type
-		fval := v.Field(i)
-		tagData := parseTagData(field.Tag)
-		// Try to find a mapping from the struct field to the command line flag
-		data, ok := rawData[name]
-		if !ok {
-			// Didn't find any flag that matches this name, try the short flag
-			if data, ok = rawData[tagData.ShortFlag]; !ok {
-				// Nothing was found. If it's not required, that's OK. Otherwise, error.
-				if !tagData.Required {
-					continue
-				} else {
-					return fmt.Errorf("%s: required argument was not supplied: --%s", os.Args[0], name)
-				}
+	}
+	for i := range l.posIndex {
+		l.posIndex[i] += offset
+	}
+	levels := []*level{{
+		spec:        s,
+		positionals: l.positionals,
+		posIndex:    l.posIndex,
+		rest:        l.rest,
+		restIndex:   offset + l.restIndex,
+	}}
+	if len(s.commands) == 0 || (l.command == nil && s.runner() != nil) {
+		return levels, nil
+	}
+	if l.command == nil {
+		return nil, fmt.Errorf("%s: missing command, expected %s", s.parser.name(), orList(s.commandNames()))
+	}
+	sub, err := s.selectCommand(l.command[0])
+	if err != nil {
+		return nil, err
+	}
+	more, err := sub.lexLevels(l.command[1:], offset+len(args)-len(l.command)+1, st)
+	if err != nil {
+		return nil, err
+	}
+	return append(levels, more...), nil
+}
+
+// apply assigns values and the positional arguments to the fields of the
+// level's struct. It carries on past values that can't be assigned, and
+// returns every error, so that they can be reported together.
+func (l *level) apply(st *parseState) []error {
+	s := l.spec
+	var errs []error
+	for _, opt := range s.flags {
+		// The lexer files short flags under the name of their field. If
+		// the flag wasn't given, fall back to the environment, then to the
+		// config files.
+		set := Setting{Source: SourceArgs}
+		var err error
+		if data, ok := st.values[opt]; ok {
+			err = s.parser.setOption(opt, data)
+			set.Arg = st.index[opt]
+		} else if st.shadowed[opt] {
+			set.Source = SourceDefault
+			if opt.tag.Default != nil {
+				err = s.parser.setDefault(opt)
 			}
+		} else if ok, envErr := s.parser.setFromEnv(opt); envErr != nil || ok {
+			err = envErr
+			set.Source, set.Env = SourceEnv, opt.env
+		} else if cv, ok := st.config[opt]; ok {
+			err = s.parser.setFromConfig(opt, cv)
+			set.Source, set.File, set.Line = SourceConfig, cv.file, cv.line
+		} else if opt.tag.Default != nil {
+			err = s.parser.setDefault(opt)
+			set.Source = SourceDefault
+		} else if opt.tag.Required {
+			// Nothing was found, and that's only OK if it's not required.
+			errs = append(errs, fmt.Errorf(
+				"%s: required argument was not supplied: --%s", s.parser.name(), opt.name))
+			continue
+		} else {
+			set.Source = SourceDefault
 		}
-		if ftype.Kind() == reflect.Ptr {
-			ftype = ftype.Elem()
-			fval.Set(reflect.New(fval.Type().Elem()))
-			fval = fval.Elem()
+		if err != nil {
+			errs = append(errs, err)
+			st.failed[opt] = true
+			continue
 		}
-		switch ftype.Kind() {
-		case reflect.String:
-			if err := checkArgLen(data, name); err != nil {
-				return fmt.Errorf("args: %s", err)
+		st.record(s, opt, set)
+	}
+	positionals, index := l.positionals, l.posIndex
+	rest := Setting{Source: SourceDefault}
+	if s.rest != nil {
+		if l.rest != nil {
+			rest = Setting{Source: SourceArgs, Arg: l.restIndex}
+			if err := s.parser.setOption(s.rest, l.rest); err != nil {
+				errs = append(errs, err)
+				st.failed[s.rest] = true
 			}
-			fval.Set(reflect.ValueOf(data[0]))
+		}
+	} else {
+		positionals = append(positionals, l.rest...)
+		for i := range l.rest {
+			index = append(index, l.restIndex+i)
+		}
+	}
+	errs = append(errs, s.setPositionals(positionals, index, st)...)
+	if s.rest != nil && !st.failed[s.rest] {
+		st.record(s, s.rest, rest)
+	}
+	return errs
+}
 
-		case reflect.Bool:
-			if len(data) != 0 {
-				return fmt.Errorf("args: boolean option %s has parameter", name)
-			}
-			fval.Set(reflect.ValueOf(true))
+// setDefault assigns the default in the tag of opt to it, unless opt
+// already holds a value other than its zero value. The default of a slice
+// is split on commas.
+func (p *Parser) setDefault(opt *option) error {
+	if !opt.value.IsZero() {
+		return nil
+	}
+	def := *opt.tag.Default
+	data := []string{def}
+	if kindOf(opt.field.Type) == reflect.Slice {
+		data = []string{}
+		if def != "" {
+			data = strings.Split(def, ",")
+		}
+	}
+	return p.setText(opt, data)
+}
 
-		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-			if err := checkArgLen(data, name); err != nil {
-				return fmt.Errorf("args: %s", err)
-			}
-			n, err := strconv.ParseInt(data[0], 0, ftype.Bits())
-			if err != nil {
-				return fmt.Errorf("args: %s", err)
+// setOption assigns data to opt. Unlike setField, it knows the tag of opt.
+func (p *Parser) setOption(opt *option, data []string) error {
+	if opt.tag.Count {
+		return opt.setCount(data)
+	}
+	if isTime(opt.field.Type) {
+		return opt.setTimes(data, p.now())
+	}
+	data, err := opt.checkChoices(data, p.IgnoreCase || opt.tag.IgnoreCase)
+	if err != nil {
+		return err
+	}
+	if data, err = opt.checkNet(data); err != nil {
+		return err
+	}
+	if opt.tag.SI {
+		scaled := make([]string, len(data))
+		for i, s := range data {
+			if scaled[i], err = parseSI(s); err != nil {
+				return fmt.Errorf("args: option %s: %s", opt.name, err)
 			}
-			fval.SetInt(n)
+		}
+		data = scaled
+	}
+	return setField(opt.value, opt.name, data)
+}
 
-		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-			if err := checkArgLen(data, name); err != nil {
-				return fmt.Errorf("args: %s", err)
-			}
-			n, err := strconv.ParseUint(data[0], 0, ftype.Bits())
-			if err != nil {
-				return fmt.Errorf("args: %s", err)
-			}
-			fval.SetUint(n)
+// setField converts data and assigns it to v. name is used in error messages.
+func setField(v reflect.Value, name string, data []string) error {
+	ftype := v.Type()
+	if ftype.Kind() == reflect.Ptr {
+		ftype = ftype.Elem()
+		v.Set(reflect.New(ftype))
+		v = v.Elem()
+	}
+	if ok, err := setCustom(v, name, data); ok {
+		return err
+	}
+	if parse := builtins[ftype]; parse != nil {
+		if err := checkArgLen(data, name); err != nil {
+			return err
+		}
+		val, err := parse(data[0])
+		if err != nil {
+			return fmt.Errorf("args: option %s: %s", name, err)
+		}
+		v.Set(reflect.ValueOf(val))
+		return nil
+	}
+	switch ftype.Kind() {
+	case reflect.String:
+		if err := checkArgLen(data, name); err != nil {
+			return fmt.Errorf("args: %s", err)
+		}
+		v.Set(reflect.ValueOf(data[0]).Convert(ftype))
 
-		case reflect.Float32, reflect.Float64:
-			if err := checkArgLen(data, name); err != nil {
-				return fmt.Errorf("args: %s", err)
+	case reflect.Bool:
+		// A boolean is true if it is switched on, and otherwise takes the
+		// last of its values, so that --no-x can follow --x.
+		b := true
+		if len(data) > 0 {
+			var err error
+			if b, err = parseBool(data[len(data)-1]); err != nil {
+				return fmt.Errorf("args: option %s: %s", name, err)
 			}
-			n, err := strconv.ParseFloat(data[0], ftype.Bits())
-			if err != nil {
-				return fmt.Errorf("args: %s", err)
-			}
-			fval.SetFloat(n)
+		}
+		v.SetBool(b)
 
-		case reflect.Slice:
-			if err := fillSlice(fval, data); err != nil {
-				return err
-			}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if err := checkArgLen(data, name); err != nil {
+			return fmt.Errorf("args: %s", err)
+		}
+		n, err := strconv.ParseInt(data[0], 0, ftype.Bits())
+		if err != nil {
+			return fmt.Errorf("args: %s", err)
+		}
+		v.SetInt(n)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if err := checkArgLen(data, name); err != nil {
+			return fmt.Errorf("args: %s", err)
+		}
+		n, err := strconv.ParseUint(data[0], 0, ftype.Bits())
+		if err != nil {
+			return fmt.Errorf("args: %s", err)
+		}
+		v.SetUint(n)
 
-		default:
-			return fmt.Errorf(
-				"args: unsupported type: %s", field.Type.Kind().String())
+	case reflect.Float32, reflect.Float64:
+		if err := checkArgLen(data, name); err != nil {
+			return fmt.Errorf("args: %s", err)
 		}
+		n, err := strconv.ParseFloat(data[0], ftype.Bits())
+		if err != nil {
+			return fmt.Errorf("args: %s", err)
+		}
+		v.SetFloat(n)
+
+	case reflect.Slice:
+		if err := fillSlice(v, name, data); err != nil {
+			return err
+		}
+
+	default:
+		return fmt.Errorf(
+			"args: unsupported type: %s", ftype.Kind().String())
 	}
 	return nil
 }
 
-func fillSlice(v reflect.Value, args []string) error {
+func fillSlice(v reflect.Value, name string, args []string) error {
 	typ := v.Type()
 	elem := typ.Elem()
 	slice := reflect.MakeSlice(typ, len(args), len(args))
+	if isValue(elem) {
+		for i, s := range args {
+			if err := setField(slice.Index(i), name, []string{s}); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+		return nil
+	}
 	switch elem.Kind() {
 	case reflect.String:
 		for i, s := range args {
@@ -341,49 +854,133 @@ func fillSlice(v reflect.Value, args []string) error {
 	return nil
 }
 
-// munch items from args, while a new key does not appear.
-// return the munched items.
-func munchArgs(args []string) []string {
-	result := []string{}
-	for len(args) > 0 {
-		if !strings.HasPrefix(args[0], "--") {
-			result = append(result, args[0])
-			args = args[1:]
-		} else {
-			break
-		}
+// rawArgsMap parses a command-line string into a map. A map has no place
+// for positional arguments, so they are an error.
+func rawArgsMap(args []string) (map[string][]string, error) {
+	l, err := lexArgs(args, nil)
+	if err != nil {
+		return nil, err
+	}
+	positionals := append(l.positionals, l.rest...)
+	if len(positionals) > 0 {
+		return nil, fmt.Errorf("args: unexpected argument %q", positionals[0])
 	}
-	return result
+	return l.flags, nil
 }
 
-// rawArgsMap parses a command-line string into a map
-func rawArgsMap(args []string) (map[string][]string, error) {
+// lexed holds the arguments, as split up by lexArgs.
+type lexed struct {
+	flags       map[string][]string
+	index       map[string]int // the index in args of the last argument of each flag
+	positionals []string
+	posIndex    []int    // the index in args of each positional argument
+	rest        []string // the arguments after "--", or nil if there was none
+	restIndex   int      // the index in args of the first of rest
+	command     []string // the command and the arguments after it, or nil
+}
+
+// lexArgs splits args into flags, positional arguments, and the arguments
+// that follow a "--" terminator. If s is not nil, it decides how many
+// values each flag takes, and flags are keyed by the name of their field,
+// or as they were given, dashes and all, if there is no such field.
+// Otherwise, flags munch values until the next flag, and are keyed as they
+// were given. If s has commands, lexing stops at the first positional
+// argument, which names the command.
+func lexArgs(args []string, s *spec) (*lexed, error) {
 	result := make(map[string][]string)
+	l := &lexed{flags: result, index: make(map[string]int), positionals: []string{}}
+	n, i := len(args), 0
+	add := func(flag string, vals ...string) {
+		if len(vals) == 0 {
+			vals = s.bareValues(flag)
+		}
+		name := s.canonical(flag)
+		if result[name] == nil {
+			result[name] = []string{}
+		}
+		result[name] = append(result[name], vals...)
+		l.index[name] = i
+	}
 	for len(args) > 0 {
-		if strings.HasPrefix(args[0], "--") {
-			key := args[0][2:]
-			args = args[1:]
-			vals := munchArgs(args)
-			result[key] = append(result[key], vals...)
+		i = n - len(args)
+		arg := args[0]
+		args = args[1:]
+		if arg == "--" {
+			// End of options. Everything else is passed through verbatim.
+			l.rest = append([]string{}, args...)
+			l.restIndex = i + 1
+			break
+		} else if strings.HasPrefix(arg, "--") {
+			key := arg[2:]
+			if i := strings.Index(key, "="); i >= 0 {
+				// --name=value always takes exactly one value
+				if s.negation(key[:i]) != nil {
+					return nil, fmt.Errorf("args: option --%s doesn't take a value", key[:i])
+				}
+				if err := s.checkLong(key[:i], arg); err != nil {
+					return nil, err
+				}
+				add(arg[:2+i], key[i+1:])
+				continue
+			}
+			if opt := s.negation(key); opt != nil {
+				add("--"+opt.name, "false")
+				continue
+			}
+			if err := s.checkLong(key, arg); err != nil {
+				return nil, err
+			}
+			vals := s.munchArgs(args, arg)
+			add(arg, vals...)
 			args = args[len(vals):]
-		} else if strings.HasPrefix(args[0], "-") {
-			if len(args[0]) > 2 {
-				// Bunch of switches stuck together
-				for i := 2; i < len(args[0]); i++ {
-					key := args[0][i-1 : i]
-					result[key] = []string{}
+		} else if s != nil && s.negativeValue(arg, s.positionalAt(len(l.positionals))) {
+			l.positionals = append(l.positionals, arg)
+			l.posIndex = append(l.posIndex, i)
+		} else if strings.HasPrefix(arg, "-") && len(arg) > 1 {
+			if len(arg) == 2 {
+				key := arg[1:]
+				if err := s.checkShort(key, arg); err != nil {
+					return nil, err
 				}
-				args = args[1:]
-			} else {
-				key := args[0][1:]
-				args = args[1:]
-				vals := munchArgs(args)
-				result[key] = append(result[key], vals...)
+				vals := s.munchArgs(args, arg)
+				add(arg, vals...)
+				args = args[len(vals):]
+				continue
+			}
+			// Bunch of switches stuck together. The first one that takes
+			// a value ends the cluster.
+			cluster := arg[1:]
+			for j := 0; j < len(cluster); j++ {
+				key := cluster[j : j+1]
+				if err := s.checkShort(key, arg); err != nil {
+					return nil, err
+				}
+				if !s.takesValue("-" + key) {
+					add("-" + key)
+					continue
+				}
+				if attached := cluster[j+1:]; attached != "" {
+					if s.allShort(attached) {
+						return nil, fmt.Errorf(
+							"args: option -%s takes a value, so it must come last in %s", key, arg)
+					}
+					// -fVALUE: the rest of the argument is the value of -f
+					add("-"+key, attached)
+					break
+				}
+				vals := s.munchArgs(args, "-"+key)
+				add("-"+key, vals...)
 				args = args[len(vals):]
 			}
+		} else if len(s.commandNames()) > 0 {
+			l.command = append([]string{arg}, args...)
+			break
+		} else {
+			l.positionals = append(l.positionals, arg)
+			l.posIndex = append(l.posIndex, i)
 		}
 	}
-	return result, nil
+	return l, nil
 }
 
 func parseMap(v reflect.Value, args []string) error {
@@ -392,6 +989,16 @@ func parseMap(v reflect.Value, args []string) error {
 	if err != nil {
 		return err
 	}
+	if isCustom(elem) {
+		for key, value := range rawData {
+			val := reflect.New(elem).Elem()
+			if err := setField(val, key, value); err != nil {
+				return err
+			}
+			v.SetMapIndex(reflect.ValueOf(key), val)
+		}
+		return nil
+	}
 	switch elem.Kind() {
 	case reflect.String:
 		for key, value := range rawData {
@@ -435,9 +1042,15 @@ func getType(data interface{}) (reflect.Type, error) {
 	typ := reflect.TypeOf(data)
 	var err error
 
+	if typ == nil {
+		return nil, errors.New("args: nil value")
+	}
 	if typ.Kind() != reflect.Ptr {
 		return typ, fmt.Errorf("args: non-pointer %s", typ.Kind().String())
 	}
+	if reflect.ValueOf(data).IsNil() {
+		return typ, fmt.Errorf("args: nil %s", typ)
+	}
 
 	typ = typ.Elem()
 	switch typ.Kind() {
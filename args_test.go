@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
@@ -28,6 +30,12 @@ func TestRawArgsMap(t *testing.T) {
 	}
 }
 
+func TestRawArgsMapPositional(t *testing.T) {
+	if _, err := rawArgsMap([]string{"asdf", "--foo"}); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
 func TestSlice(t *testing.T) {
 	args := []string{
 		"--foo",
@@ -135,7 +143,7 @@ func TestMap(t *testing.T) {
 
 func TestStruct(t *testing.T) {
 	type Test struct {
-		Int8    int8 `args:"this is an int,r,3"`
+		Int8    int8 `args:"this is an int,r"`
 		Int16   int16
 		Int32   int32
 		Int64   int64
@@ -297,7 +305,7 @@ func TestUsage(t *testing.T) {
 	}
 
 	want := []string{
-		"usage:",
+		"usage: " + filepath.Base(os.Args[0]) + " [options]",
 		" -s,\t--salad\t(default: \"\")\ttype of salad to eat",
 		" -p,\t--pie\t(default: 0)\tnumber of pies to eat",
 		" \t--nachos\t\tnacho quotient",
@@ -314,6 +322,126 @@ func TestUsage(t *testing.T) {
 	}
 }
 
+func TestPositionals(t *testing.T) {
+	type Test struct {
+		Verbose bool     `args:"be chatty,-v"`
+		Level   int      `args:"a level,-l"`
+		Src     string   `args:"source,pos"`
+		Count   int      `args:"how many,pos"`
+		Rest    []string `args:"the rest,pos"`
+	}
+
+	var got Test
+	testArgs := []string{"-v", "a", "--level", "3", "4", "b", "-l", "5", "c"}
+	if err := parse(&got, testArgs); err == nil {
+		t.Fatal("expected error")
+	}
+
+	got = Test{}
+	testArgs = []string{"-v", "a", "--level", "3", "4", "b", "c"}
+	if err := parse(&got, testArgs); err != nil {
+		t.Fatal(err)
+	}
+	want := Test{Verbose: true, Level: 3, Src: "a", Count: 4, Rest: []string{"b", "c"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("bad data: got %+v, want %+v", got, want)
+	}
+
+	got = Test{}
+	if err := parse(&got, []string{"a", "4"}); err != nil {
+		t.Fatal(err)
+	}
+	if got.Rest != nil {
+		t.Fatalf("bad data: got %v, want nil", got.Rest)
+	}
+
+	// Missing a required positional
+	if err := parse(&got, []string{"a", "-v"}); err == nil {
+		t.Fatal("expected error")
+	}
+	// Bad type
+	if err := parse(&got, []string{"a", "b"}); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestPositionalsEmbedded(t *testing.T) {
+	type Test struct {
+		Positionals
+		Verbose bool   `args:"be chatty,-v"`
+		Name    string `args:"a name"`
+		First   *int   `args:"first,pos"`
+	}
+
+	var got Test
+	testArgs := []string{"1", "--name", "foo", "a", "-v", "b"}
+	if err := parse(&got, testArgs); err != nil {
+		t.Fatal(err)
+	}
+	if got.First == nil || *got.First != 1 {
+		t.Fatalf("bad first: %v", got.First)
+	}
+	if want := []string{"a", "b"}; !reflect.DeepEqual(got.Args(), want) {
+		t.Fatalf("bad data: got %v, want %v", got.Args(), want)
+	}
+	if got.NArg() != 2 || got.Arg(1) != "b" || got.Arg(2) != "" {
+		t.Fatalf("bad data: %+v", got)
+	}
+
+	type NoPositionals struct {
+		Verbose bool `args:"be chatty,-v"`
+	}
+	var np NoPositionals
+	if err := parse(&np, []string{"-v", "a"}); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestPositionalsSpec(t *testing.T) {
+	type AfterVariadic struct {
+		A []string `args:",pos"`
+		B string   `args:",pos"`
+	}
+	type RequiredAfterOptional struct {
+		A *string `args:",pos"`
+		B string  `args:",pos"`
+	}
+	if err := parse(&AfterVariadic{}, nil); err == nil {
+		t.Fatal("expected error")
+	}
+	if err := parse(&RequiredAfterOptional{}, nil); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+type PositionalUsageTest struct {
+	Positionals
+	Force bool     `args:"overwrite,-f"`
+	Src   string   `args:"source file,pos"`
+	Dst   *string  `args:"destination,pos"`
+	More  []string `args:"more files,pos"`
+}
+
+func TestPositionalUsage(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Usage(&buf, PositionalUsageTest{}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"usage: " + filepath.Base(os.Args[0]) + " [options] SRC [DST] [MORE...] [ARGS...]",
+		" -f,\t--[no-]force\t(default: false)\toverwrite",
+		" \tSRC\t\tsource file",
+		" \tDST\t\tdestination",
+		" \tMORE...\t\tmore files",
+		"",
+	}
+
+	if got := strings.Split(buf.String(), "\n"); !reflect.DeepEqual(got, want) {
+		t.Fatalf("bad usage: got %q, want %q", got, want)
+	}
+}
+
 func TestCheckArgLen(t *testing.T) {
 	a := []string{}
 	b := []string{"a"}
@@ -357,3 +485,310 @@ func TestGetType(t *testing.T) {
 		t.Fatal("expected error")
 	}
 }
+
+func TestTerminator(t *testing.T) {
+	type Test struct {
+		Positionals
+		Verbose bool   `args:"be chatty,-v"`
+		Name    string `args:"a name"`
+	}
+
+	var got Test
+	testArgs := []string{"-v", "a", "--", "--name", "-x", "--"}
+	if err := parse(&got, testArgs); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a", "--name", "-x", "--"}; !reflect.DeepEqual(got.Args(), want) {
+		t.Fatalf("bad data: got %q, want %q", got.Args(), want)
+	}
+	if got.Name != "" {
+		t.Fatalf("bad name: %q", got.Name)
+	}
+
+	m := map[string]string{}
+	if err := parse(&m, []string{"--foo", "bar", "--", "baz"}); err == nil {
+		t.Fatal("expected error")
+	}
+	if err := parse(&m, []string{"--foo", "bar", "--"}); err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]string{"foo": "bar"}; !reflect.DeepEqual(m, want) {
+		t.Fatalf("bad data: got %v, want %v", m, want)
+	}
+}
+
+func TestRest(t *testing.T) {
+	type Test struct {
+		Verbose bool     `args:"be chatty,-v"`
+		Host    string   `args:"host,pos"`
+		Rest    []string `args:"ssh options,rest"`
+	}
+
+	var got Test
+	testArgs := []string{"example.com", "-v", "--", "-p", "22", "--", "ls"}
+	if err := parse(&got, testArgs); err != nil {
+		t.Fatal(err)
+	}
+	want := Test{Verbose: true, Host: "example.com", Rest: []string{"-p", "22", "--", "ls"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("bad data: got %+v, want %+v", got, want)
+	}
+
+	// Arguments after -- don't fill positionals when there is a rest field.
+	got = Test{}
+	if err := parse(&got, []string{"--", "example.com"}); err == nil {
+		t.Fatal("expected error")
+	}
+
+	got = Test{}
+	if err := parse(&got, []string{"example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	if got.Rest != nil {
+		t.Fatalf("bad rest: %q", got.Rest)
+	}
+
+	type BadRest struct {
+		Rest []int `args:",rest"`
+	}
+	type TwoRests struct {
+		A []string `args:",rest"`
+		B []string `args:",rest"`
+	}
+	if err := parse(&BadRest{}, nil); err == nil {
+		t.Fatal("expected error")
+	}
+	if err := parse(&TwoRests{}, nil); err == nil {
+		t.Fatal("expected error")
+	}
+
+	var buf bytes.Buffer
+	if err := Usage(&buf, Test{}); err != nil {
+		t.Fatal(err)
+	}
+	wantUsage := []string{
+		"usage: " + filepath.Base(os.Args[0]) + " [options] HOST [-- REST...]",
+		" -v,\t--[no-]verbose\t(default: false)\tbe chatty",
+		" \tHOST\t\thost",
+		" \tREST...\t\tssh options",
+		"",
+	}
+	if got := strings.Split(buf.String(), "\n"); !reflect.DeepEqual(got, wantUsage) {
+		t.Fatalf("bad usage: got %q, want %q", got, wantUsage)
+	}
+}
+
+func TestAttachedValues(t *testing.T) {
+	type Test struct {
+		Foo    int      `args:"a foo,-f"`
+		Baz    string   `args:"a baz,-z"`
+		Slice  []string `args:"a slice,-s"`
+		Switch bool     `args:"a switch,-w"`
+	}
+
+	var got Test
+	testArgs := []string{"--baz=a=b", "-f5", "--slice=-x", "-s--y", "--slice", "c"}
+	if err := parse(&got, testArgs); err != nil {
+		t.Fatal(err)
+	}
+	want := Test{Foo: 5, Baz: "a=b", Slice: []string{"-x", "--y", "c"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("bad data: got %+v, want %+v", got, want)
+	}
+
+	got = Test{}
+	if err := parse(&got, []string{"--baz=", "-z-"}); err == nil {
+		t.Fatal("expected error")
+	}
+	got = Test{}
+	if err := parse(&got, []string{"--baz=", "--foo=-3"}); err != nil {
+		t.Fatal(err)
+	}
+	if want := (Test{Foo: -3}); !reflect.DeepEqual(got, want) {
+		t.Fatalf("bad data: got %+v, want %+v", got, want)
+	}
+	if err := parse(&got, []string{"--switch=x"}); err == nil {
+		t.Fatal("expected error")
+	}
+
+	m := map[string]string{}
+	if err := parse(&m, []string{"--foo=bar=baz", "--qux="}); err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]string{"foo": "bar=baz", "qux": ""}; !reflect.DeepEqual(m, want) {
+		t.Fatalf("bad data: got %v, want %v", m, want)
+	}
+}
+
+func TestShortFlagsOfOtherFields(t *testing.T) {
+	type Test struct {
+		N       int
+		Name    string `args:"name,-n"`
+		V       string
+		Verbose bool `args:"verbose,-v"`
+	}
+	var got Test
+	if err := parse(&got, []string{"-n", "bob", "-v", "--n", "3", "--v=x"}); err != nil {
+		t.Fatal(err)
+	}
+	want := Test{N: 3, Name: "bob", V: "x", Verbose: true}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("bad data: got %+v, want %+v", got, want)
+	}
+}
+
+func TestClusters(t *testing.T) {
+	type Test struct {
+		Extract bool     `args:"extract,-x"`
+		Verbose bool     `args:"be chatty,-v"`
+		Zip     bool     `args:"compress,-z"`
+		File    string   `args:"archive,-f"`
+		Names   []string `args:"names,-n"`
+		Members []string `args:"members,pos"`
+	}
+
+	var got Test
+	if err := parse(&got, []string{"-xvz"}); err != nil {
+		t.Fatal(err)
+	}
+	if want := (Test{Extract: true, Verbose: true, Zip: true}); !reflect.DeepEqual(got, want) {
+		t.Fatalf("bad data: got %+v, want %+v", got, want)
+	}
+
+	got = Test{}
+	if err := parse(&got, []string{"-xvf", "archive.tar", "a", "b"}); err != nil {
+		t.Fatal(err)
+	}
+	want := Test{Extract: true, Verbose: true, File: "archive.tar", Members: []string{"a", "b"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("bad data: got %+v, want %+v", got, want)
+	}
+
+	got = Test{}
+	if err := parse(&got, []string{"-zfarchive.tgz", "-vn", "a", "b"}); err != nil {
+		t.Fatal(err)
+	}
+	want = Test{Verbose: true, Zip: true, File: "archive.tgz", Names: []string{"a", "b"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("bad data: got %+v, want %+v", got, want)
+	}
+
+	// -f takes a value, so it can't be followed by other switches
+	got = Test{}
+	err := parse(&got, []string{"-xfv", "archive.tar"})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if !strings.Contains(err.Error(), "-f") {
+		t.Fatalf("bad error: %s", err)
+	}
+
+	got2, err := rawArgsMap([]string{"-abc", "--foo", "bar"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want2 := map[string][]string{"a": {}, "b": {}, "c": {}, "foo": {"bar"}}
+	if !reflect.DeepEqual(got2, want2) {
+		t.Fatalf("got %+v, want %+v", got2, want2)
+	}
+}
+
+func TestNegativeNumbers(t *testing.T) {
+	type Test struct {
+		Offset int       `args:"an offset,-o"`
+		Deltas []float64 `args:"deltas,-d"`
+		Name   string    `args:"a name,-n"`
+		Quiet  bool      `args:"be quiet,-q"`
+		Start  int       `args:"start,pos"`
+	}
+
+	var got Test
+	testArgs := []string{"--offset", "-5", "-q", "-3", "--deltas", "-1", "-2.5", "3", "-o-7", "-n", "x"}
+	if err := parse(&got, testArgs); err == nil {
+		t.Fatal("expected error")
+	}
+
+	got = Test{}
+	testArgs = []string{"--offset", "-5", "-q", "-3", "--deltas", "-1", "-2.5", "3", "-n", "x"}
+	if err := parse(&got, testArgs); err != nil {
+		t.Fatal(err)
+	}
+	want := Test{Offset: -5, Deltas: []float64{-1, -2.5, 3}, Name: "x", Quiet: true, Start: -3}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("bad data: got %+v, want %+v", got, want)
+	}
+
+	// -5 is not numeric, so it isn't a value of --name
+	got = Test{}
+	if err := parse(&got, []string{"--name", "-5", "1"}); err == nil {
+		t.Fatal("expected error")
+	}
+	got = Test{}
+	if err := parse(&got, []string{"--name=-5", "1"}); err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "-5" {
+		t.Fatalf("bad name: %q", got.Name)
+	}
+
+	// Declaring a digit short flag makes negative numbers that start with
+	// that digit flags
+	type Digit struct {
+		Offset int  `args:"an offset"`
+		Five   bool `args:"five,-5"`
+	}
+	var d Digit
+	if err := parse(&d, []string{"--offset", "-5"}); err == nil {
+		t.Fatal("expected error")
+	}
+	d = Digit{}
+	if err := parse(&d, []string{"-5"}); err != nil {
+		t.Fatal(err)
+	}
+	if !d.Five {
+		t.Fatal("expected -5 to be a flag")
+	}
+	d = Digit{}
+	if err := parse(&d, []string{"--offset", "-7"}); err != nil {
+		t.Fatal(err)
+	}
+	if d.Offset != -7 || d.Five {
+		t.Fatalf("bad data: %+v", d)
+	}
+
+	m := map[string]interface{}{}
+	if err := parse(&m, []string{"--foo", "-5"}); err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]interface{}{"foo": int64(-5)}; !reflect.DeepEqual(m, want) {
+		t.Fatalf("bad data: got %v, want %v", m, want)
+	}
+}
+
+func TestStrictNumbers(t *testing.T) {
+	type Test struct {
+		Positionals
+		Name string `args:"a name,-n"`
+	}
+
+	p := &Parser{StrictNumbers: true}
+	var got Test
+	if err := p.parse(&got, []string{"--name", "-5", "-1.5"}); err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "-5" || !reflect.DeepEqual(got.Args(), []string{"-1.5"}) {
+		t.Fatalf("bad data: %+v", got)
+	}
+
+	got = Test{}
+	if err := parse(&got, []string{"--name", "-5"}); err == nil {
+		t.Fatal("expected error")
+	}
+
+	type Digit struct {
+		Five bool `args:"five,-5"`
+	}
+	if err := p.parse(&Digit{}, nil); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -0,0 +1,45 @@
+package args
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// parseBool parses s as a boolean. On top of what strconv.ParseBool takes,
+// such as true, false, 1 and 0, it takes yes and no, in any case.
+func parseBool(s string) (bool, error) {
+	switch strings.ToLower(s) {
+	case "yes":
+		return true, nil
+	case "no":
+		return false, nil
+	}
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return false, fmt.Errorf("%q is not true or false", s)
+	}
+	return b, nil
+}
+
+// negatable is true if opt is a boolean flag that can be turned off with
+// --no- in front of its long flags.
+func (opt *option) negatable() bool {
+	return !opt.tag.NoNegate && !opt.tag.Positional && !opt.tag.Rest && kindOf(opt.field.Type) == reflect.Bool
+}
+
+// negation returns the boolean flag that key turns off, as no-color turns
+// off color, or nil if there is none. A flag that is really called key
+// takes precedence.
+func (s *spec) negation(key string) *option {
+	if s.lookupLong(key) != nil {
+		return nil
+	}
+	for ; s != nil; s = s.parent {
+		if opt, ok := s.negated[key]; ok {
+			return opt
+		}
+	}
+	return nil
+}
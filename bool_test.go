@@ -0,0 +1,92 @@
+package args
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestNegatableBools(t *testing.T) {
+	type Test struct {
+		Color  bool  `args:"help='use color' default=true"`
+		Cache  *bool `args:"help='use the cache' short=c"`
+		Force  bool  `args:"help=force short=f nonegate"`
+		Follow bool  `args:"help=follow long=follow long=tail"`
+	}
+	tests := []struct {
+		args   []string
+		color  bool
+		cache  *bool
+		force  bool
+		follow bool
+	}{
+		{args: nil, color: true},
+		{args: []string{"--no-color"}, color: false},
+		{args: []string{"--color=false"}, color: false},
+		{args: []string{"--color=no", "--color"}, color: true},
+		{args: []string{"--color", "--no-color"}, color: false},
+		{args: []string{"--color=YES"}, color: true},
+		{args: []string{"-c"}, color: true, cache: newBool(true)},
+		{args: []string{"--no-cache"}, color: true, cache: newBool(false)},
+		{args: []string{"--cache=0"}, color: true, cache: newBool(false)},
+		{args: []string{"-fc", "--force=1"}, color: true, cache: newBool(true), force: true},
+		{args: []string{"--tail", "--no-tail"}, color: true, follow: false},
+		{args: []string{"--no-follow", "--tail"}, color: true, follow: true},
+	}
+	for _, test := range tests {
+		var got Test
+		if err := ParseArgs(&got, test.args); err != nil {
+			t.Fatalf("%q: %s", test.args, err)
+		}
+		if got.Color != test.color || got.Force != test.force || got.Follow != test.follow {
+			t.Fatalf("%q: bad data: %+v", test.args, got)
+		}
+		if (got.Cache == nil) != (test.cache == nil) || (got.Cache != nil && *got.Cache != *test.cache) {
+			t.Fatalf("%q: bad cache: got %v, want %v", test.args, got.Cache, test.cache)
+		}
+	}
+
+	for _, test := range []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"--color=maybe"}, `option color: "maybe" is not true or false`},
+		{[]string{"--no-color=true"}, `option --no-color doesn't take a value`},
+		{[]string{"--no-force"}, `unknown flag --no-force`},
+	} {
+		err := ParseArgs(&Test{}, test.args)
+		if err == nil {
+			t.Fatalf("%q: expected error", test.args)
+		}
+		if !strings.Contains(err.Error(), test.err) {
+			t.Fatalf("%q: bad error: %s", test.args, err)
+		}
+	}
+
+	type Clash struct {
+		Color   bool `args:"use color"`
+		NoColor bool `args:"don't use color"`
+	}
+	if err := ParseArgs(&Clash{}, nil); err == nil {
+		t.Fatal("expected error")
+	}
+
+	var buf bytes.Buffer
+	if err := Usage(&buf, Test{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"\t--[no-]color\t(default: true)",
+		" -c,\t--[no-]cache\t\t",
+		" -f,\t--force\t",
+		"\t--[no-]follow, --[no-]tail\t",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Fatalf("usage is missing %q:\n%s", want, buf.String())
+		}
+	}
+}
+
+func newBool(b bool) *bool {
+	return &b
+}
@@ -0,0 +1,74 @@
+package args
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Enum is implemented by types that can only take certain values, such as
+// log levels. Arguments for fields of the type must be one of the values
+// that Choices returns, unless the choices tag key lists others.
+type Enum interface {
+	Choices() []string
+}
+
+// choices returns the values that opt can take, or nil if it can take any
+// value.
+func (opt *option) choices() []string {
+	if len(opt.tag.Choices) > 0 {
+		return opt.tag.Choices
+	}
+	if e, ok := reflect.New(baseType(opt.field.Type)).Interface().(Enum); ok {
+		return e.Choices()
+	}
+	return nil
+}
+
+// checkChoices makes sure that each of data is one of the choices of opt.
+// If ignoreCase is true, matching ignores case, and the values are
+// returned as the choices spell them.
+func (opt *option) checkChoices(data []string, ignoreCase bool) ([]string, error) {
+	choices := opt.choices()
+	if len(choices) == 0 {
+		return data, nil
+	}
+	result := make([]string, len(data))
+	for i, s := range data {
+		var ok bool
+		for _, c := range choices {
+			if s == c || (ignoreCase && strings.EqualFold(s, c)) {
+				result[i], ok = c, true
+				break
+			}
+		}
+		if ok {
+			continue
+		}
+		msg := fmt.Sprintf("args: option %s: %q is not %s", opt.name, s, orList(choices))
+		if suggestions := closestChoices(s, choices, ignoreCase); len(suggestions) > 0 {
+			msg += ", did you mean " + orList(suggestions) + "?"
+		}
+		return nil, errors.New(msg)
+	}
+	return result, nil
+}
+
+// closestChoices returns the choices that are closest to typed.
+func closestChoices(typed string, choices []string, ignoreCase bool) []string {
+	if !ignoreCase {
+		return closest(typed, choices)
+	}
+	spelling := make(map[string]string)
+	candidates := make([]string, len(choices))
+	for i, c := range choices {
+		candidates[i] = strings.ToLower(c)
+		spelling[candidates[i]] = c
+	}
+	suggestions := closest(strings.ToLower(typed), candidates)
+	for i, sug := range suggestions {
+		suggestions[i] = spelling[sug]
+	}
+	return suggestions
+}
@@ -0,0 +1,81 @@
+package args
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// color is an Enum.
+type color string
+
+func (color) Choices() []string {
+	return []string{"Red", "Green", "Blue"}
+}
+
+func TestChoices(t *testing.T) {
+	type Test struct {
+		Format  string   `args:"output format,choices=json|yaml|text"`
+		Formats []string `args:"help='more formats' choices=json|yaml|text"`
+		Color   color    `args:"a color"`
+		Shade   *color   `args:"help='a shade' choices=light|dark ignorecase"`
+		Mode    string   `args:"help='a mode' choices=fast|safe pos"`
+	}
+	var got Test
+	testArgs := []string{"--format", "yaml", "--formats", "json", "text", "--color", "Green", "--shade", "DARK", "safe"}
+	if err := ParseArgs(&got, testArgs); err != nil {
+		t.Fatal(err)
+	}
+	dark := color("dark")
+	want := Test{
+		Format:  "yaml",
+		Formats: []string{"json", "text"},
+		Color:   "Green",
+		Shade:   &dark,
+		Mode:    "safe",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("bad data: got %+v, want %+v", got, want)
+	}
+
+	tests := []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"--format", "jsn", "fast"}, `args: option format: "jsn" is not json, yaml or text, did you mean json?`},
+		{[]string{"fast", "--formats", "yaml", "xml"}, `args: option formats: "xml" is not json, yaml or text`},
+		{[]string{"--color", "green", "fast"}, `args: option color: "green" is not Red, Green or Blue, did you mean Green?`},
+		{[]string{"--shade", "DRAK", "fast"}, `args: option shade: "DRAK" is not light or dark, did you mean dark?`},
+		{[]string{"quick"}, `args: option mode: "quick" is not fast or safe`},
+	}
+	for _, test := range tests {
+		err := ParseArgs(&Test{}, test.args)
+		if err == nil {
+			t.Fatalf("%q: expected error", test.args)
+		}
+		if err.Error() != test.err {
+			t.Fatalf("%q: bad error: got %s, want %s", test.args, err, test.err)
+		}
+	}
+
+	// The parser can ignore case for every field.
+	p := &Parser{IgnoreCase: true}
+	got = Test{}
+	if err := p.ParseArgs(&got, []string{"--color", "BLUE", "--format", "Text", "Fast"}); err != nil {
+		t.Fatal(err)
+	}
+	if got.Color != "Blue" || got.Format != "text" || got.Mode != "fast" {
+		t.Fatalf("bad data: %+v", got)
+	}
+
+	var buf bytes.Buffer
+	if err := Usage(&buf, Test{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"output format {json|yaml|text}\n", "a color {Red|Green|Blue}\n", "MODE\t\ta mode {fast|safe}\n"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Fatalf("usage is missing %q:\n%s", want, buf.String())
+		}
+	}
+}
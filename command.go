@@ -0,0 +1,156 @@
+package args
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+)
+
+// Runner is implemented by commands that Run can run.
+type Runner interface {
+	Run(ctx context.Context) error
+}
+
+// command is a struct field that holds a command.
+type command struct {
+	name  string
+	help  string
+	path  string        // the path of the command's field
+	value reflect.Value // a pointer to the command's struct
+}
+
+/*
+Run parses os.Args[1:] into strukt, like Parse, and runs the command that
+they select.
+
+Fields tagged with "cmd" are commands. A command is a pointer to a struct,
+which holds the flags, positional arguments and commands of its own. It is
+allocated when it is selected, and left alone otherwise. Its flags can be
+given anywhere after its name, along with the flags of its parents.
+
+	type Clone struct {
+		Depth int    `args:"history depth"`
+		URL   string `args:"repository,pos"`
+	}
+
+	func (c *Clone) Run(ctx context.Context) error { ... }
+
+	type Args struct {
+		Verbose bool   `args:"be chatty,-v"`
+		Clone   *Clone `args:"help='clone a repository' cmd"`
+		Remote  *struct {
+			Add *RemoteAdd `args:"help='add a remote' cmd"`
+		} `args:"help='manage remotes' cmd"`
+	}
+
+The name of a command is the same as the flag name of its field, unless
+the tag sets it, as in cmd=name.
+
+Run calls the Run method of the selected command, or of strukt if no
+command is selected, and returns its error. If that doesn't implement
+Runner, Run returns an error. A missing command is an error, unless strukt
+implements Runner.
+*/
+func Run(ctx context.Context, strukt interface{}) error {
+	return new(Parser).Run(ctx, strukt)
+}
+
+// Run parses os.Args[1:] into strukt, and runs the command that they
+// select, like the package-level Run.
+func (p *Parser) Run(ctx context.Context, strukt interface{}) error {
+	return p.RunArgs(ctx, strukt, os.Args[1:])
+}
+
+// RunArgs parses args into strukt, and runs the command that they select,
+// like the package-level Run.
+func (p *Parser) RunArgs(ctx context.Context, strukt interface{}, args []string) error {
+	typ, err := getType(strukt)
+	if err != nil {
+		return err
+	}
+	if typ.Kind() != reflect.Struct {
+		return fmt.Errorf("args: can only run a struct, not %s", typ.Kind())
+	}
+	s, err := p.parseStruct(reflect.ValueOf(strukt).Elem(), args, new(Result))
+	if err != nil {
+		return err
+	}
+	r := s.runner()
+	if r == nil {
+		return fmt.Errorf("args: %s can't be run", s.value.Type())
+	}
+	return r.Run(ctx)
+}
+
+// addCommand adds the struct field as a command.
+func (s *spec) addCommand(field reflect.StructField, path string, td tagData, g *group, fval reflect.Value) error {
+	if g != nil {
+		return fmt.Errorf("args: command %s can't be in group %s", path, g.path)
+	}
+	if field.Type.Kind() != reflect.Ptr || field.Type.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("args: command %s must be a pointer to a struct, not %s", path, field.Type)
+	}
+	name := *td.Command
+	if name == "" {
+		name = s.parser.flagName(field.Name)
+	}
+	for _, cmd := range s.commands {
+		if cmd.name == name {
+			return fmt.Errorf("args: command %s is declared more than once", name)
+		}
+	}
+	s.commands = append(s.commands, &command{name: name, help: td.Description, path: path, value: fval})
+	return nil
+}
+
+// selectCommand allocates the command called name, if need be, and
+// returns its spec.
+func (s *spec) selectCommand(name string) (*spec, error) {
+	var cmd *command
+	for _, c := range s.commands {
+		if c.name == name {
+			cmd = c
+			break
+		}
+	}
+	if cmd == nil {
+		msg := fmt.Sprintf("%s: unknown command %q", s.parser.name(), name)
+		if suggestions := closest(name, s.commandNames()); len(suggestions) > 0 {
+			msg += ", did you mean " + orList(suggestions) + "?"
+		}
+		return nil, errors.New(msg)
+	}
+	ptr := cmd.value
+	if ptr.IsNil() {
+		ptr = reflect.New(ptr.Type().Elem())
+	}
+	sub, err := newSpec(ptr.Elem(), s.parser)
+	if err != nil {
+		return nil, err
+	}
+	sub.parent = s
+	sub.path = append(append([]string{}, s.path...), name)
+	sub.fieldPath = s.fieldPath + cmd.path + "."
+	cmd.value.Set(ptr)
+	return sub, nil
+}
+
+// commandNames returns the names of the commands of s.
+func (s *spec) commandNames() []string {
+	if s == nil {
+		return nil
+	}
+	names := make([]string, len(s.commands))
+	for i, cmd := range s.commands {
+		names[i] = cmd.name
+	}
+	return names
+}
+
+// runner returns the struct of s as a Runner, or nil if it isn't one.
+func (s *spec) runner() Runner {
+	r, _ := s.value.Addr().Interface().(Runner)
+	return r
+}
@@ -0,0 +1,218 @@
+package args
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type cloneCmd struct {
+	Depth int    `args:"history depth,-d"`
+	URL   string `args:"repository,pos"`
+
+	ran bool
+}
+
+func (c *cloneCmd) Run(ctx context.Context) error {
+	c.ran = true
+	if c.URL == "fail" {
+		return errors.New("clone failed")
+	}
+	return nil
+}
+
+type remoteAddCmd struct {
+	Name string `args:"remote name,pos"`
+	URL  string `args:"remote url,pos"`
+}
+
+func (c *remoteAddCmd) Run(ctx context.Context) error {
+	return nil
+}
+
+type remoteCmd struct {
+	Add    *remoteAddCmd `args:"help='add a remote' cmd"`
+	Remove *struct {
+		Name string `args:"remote name,pos"`
+	} `args:"cmd=rm"`
+}
+
+type gitArgs struct {
+	Verbose bool       `args:"be chatty,-v"`
+	Dir     string     `args:"working directory,-C"`
+	Clone   *cloneCmd  `args:"help='clone a repository' cmd"`
+	Remote  *remoteCmd `args:"help='manage remotes' cmd"`
+}
+
+func TestCommands(t *testing.T) {
+	var got gitArgs
+	testArgs := []string{"-C", "/tmp", "clone", "-v", "--depth", "1", "https://example.com/repo"}
+	if err := ParseArgs(&got, testArgs); err != nil {
+		t.Fatal(err)
+	}
+	want := gitArgs{
+		Verbose: true,
+		Dir:     "/tmp",
+		Clone:   &cloneCmd{Depth: 1, URL: "https://example.com/repo"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("bad data: got %+v, want %+v", got, want)
+	}
+
+	got = gitArgs{}
+	testArgs = []string{"remote", "add", "origin", "-v", "https://example.com/repo"}
+	if err := ParseArgs(&got, testArgs); err != nil {
+		t.Fatal(err)
+	}
+	if got.Clone != nil || got.Remote == nil || got.Remote.Add == nil || !got.Verbose {
+		t.Fatalf("bad data: %+v", got)
+	}
+	if want := (remoteAddCmd{Name: "origin", URL: "https://example.com/repo"}); *got.Remote.Add != want {
+		t.Fatalf("bad data: got %+v, want %+v", *got.Remote.Add, want)
+	}
+
+	got = gitArgs{}
+	if err := ParseArgs(&got, []string{"remote", "rm", "origin"}); err != nil {
+		t.Fatal(err)
+	}
+	if got.Remote.Remove == nil || got.Remote.Remove.Name != "origin" {
+		t.Fatalf("bad data: %+v", got.Remote)
+	}
+
+	errTests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-v"}, "prog: missing command, expected clone or remote"},
+		{[]string{"clon", "x"}, `prog: unknown command "clon", did you mean clone?`},
+		{[]string{"-d", "1", "clone", "x"}, "args: unknown flag -d, did you mean -C or -v?"},
+		{[]string{"remote", "add", "--dept", "1"}, "args: unknown flag --dept"},
+		{[]string{"clone", "--dept", "1", "x"}, "args: unknown flag --dept, did you mean --depth?"},
+		{[]string{"clone"}, "prog: required argument was not supplied: URL"},
+	}
+	p := &Parser{Name: "prog"}
+	for _, test := range errTests {
+		err := p.ParseArgs(&gitArgs{}, test.args)
+		if err == nil {
+			t.Errorf("%q: expected error", test.args)
+		} else if err.Error() != test.want {
+			t.Errorf("%q: got error %q, want %q", test.args, err, test.want)
+		}
+	}
+}
+
+func TestRunCommands(t *testing.T) {
+	var got gitArgs
+	if err := runArgs(&got, "clone", "x"); err != nil {
+		t.Fatal(err)
+	}
+	if !got.Clone.ran {
+		t.Fatal("expected clone to run")
+	}
+
+	got = gitArgs{}
+	if err := runArgs(&got, "clone", "fail"); err == nil || err.Error() != "clone failed" {
+		t.Fatalf("bad error: %v", err)
+	}
+
+	// remote has no Run method
+	got = gitArgs{}
+	if err := runArgs(&got, "remote"); err == nil {
+		t.Fatal("expected error")
+	}
+	got = gitArgs{}
+	if err := runArgs(&got, "remote", "add", "a", "b"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A runnable command doesn't need a subcommand
+	var root runnableRoot
+	if err := runArgs(&root); err != nil {
+		t.Fatal(err)
+	}
+	if !root.ran {
+		t.Fatal("expected root to run")
+	}
+}
+
+type runnableRoot struct {
+	Clone *cloneCmd `args:"help='clone a repository' cmd"`
+
+	ran bool
+}
+
+func (r *runnableRoot) Run(ctx context.Context) error {
+	r.ran = true
+	return nil
+}
+
+func runArgs(strukt interface{}, args ...string) error {
+	return new(Parser).RunArgs(context.Background(), strukt, args)
+}
+
+func TestCommandUsage(t *testing.T) {
+	var buf bytes.Buffer
+	p := &Parser{Name: "git", Stdout: &buf}
+	if err := p.Usage(gitArgs{}); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"usage: git [options] COMMAND",
+		" -v,\t--[no-]verbose\t(default: false)\tbe chatty",
+		" -C,\t--dir\t(default: \"\")\tworking directory",
+		"",
+		"commands:",
+		" \tclone\t\tclone a repository",
+		" \tremote\t\tmanage remotes",
+		"",
+	}
+	if got := strings.Split(buf.String(), "\n"); !reflect.DeepEqual(got, want) {
+		t.Fatalf("bad usage: got %q, want %q", got, want)
+	}
+
+	buf.Reset()
+	p.Help = true
+	if err := p.ParseArgs(&gitArgs{}, []string{"clone", "--help"}); err != ErrHelp {
+		t.Fatalf("got %v, want ErrHelp", err)
+	}
+	want = []string{
+		"usage: git clone [options] URL",
+		" -d,\t--depth\t(default: 0)\thistory depth",
+		" \tURL\t\trepository",
+		"",
+		"global options:",
+		" -v,\t--[no-]verbose\t(default: false)\tbe chatty",
+		" -C,\t--dir\t(default: \"\")\tworking directory",
+		"",
+	}
+	if got := strings.Split(buf.String(), "\n"); !reflect.DeepEqual(got, want) {
+		t.Fatalf("bad usage: got %q, want %q", got, want)
+	}
+}
+
+func TestCommandErrors(t *testing.T) {
+	type NotPointer struct {
+		Clone cloneCmd `args:"help='clone a repository' cmd"`
+	}
+	type WithPositionals struct {
+		Name  string    `args:",pos"`
+		Clone *cloneCmd `args:"help='clone a repository' cmd"`
+	}
+	type Duplicate struct {
+		A *cloneCmd `args:"cmd=x"`
+		B *cloneCmd `args:"cmd=x"`
+	}
+	type InGroup struct {
+		G struct {
+			Clone *cloneCmd `args:"help='clone a repository' cmd"`
+		}
+	}
+	for _, strukt := range []interface{}{&NotPointer{}, &WithPositionals{}, &Duplicate{}, &InGroup{}} {
+		if err := ParseArgs(strukt, nil); err == nil {
+			t.Errorf("%T: expected error", strukt)
+		}
+	}
+}
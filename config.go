@@ -0,0 +1,332 @@
+package args
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// configValue is the value of a key in a config file.
+type configValue struct {
+	data []string
+	list bool // data is a list, so it isn't split on commas
+	file string
+	line int
+}
+
+func (cv *configValue) pos() string {
+	return fmt.Sprintf("%s:%d", cv.file, cv.line)
+}
+
+// configEntry is a key of a config file and its value. The key is split
+// into the names of its sections and the name of the key.
+type configEntry struct {
+	key []string
+	configValue
+}
+
+// loadConfig reads the config files that p and the flags in values name,
+// and returns the values of the options they set. Later files override
+// earlier ones.
+func (p *Parser) loadConfig(levels []*level, values map[*option][]string) (map[*option]*configValue, error) {
+	type configFile struct {
+		name     string
+		required bool
+	}
+	var files []configFile
+	for _, name := range p.ConfigFiles {
+		files = append(files, configFile{name: name})
+	}
+	for _, l := range levels {
+		for _, opt := range l.spec.flags {
+			if !opt.tag.Config {
+				continue
+			}
+			if name, given := p.configName(opt, values); name != "" {
+				files = append(files, configFile{name: name, required: given})
+			}
+		}
+	}
+	config := make(map[*option]*configValue)
+	for _, f := range files {
+		entries, err := p.readConfig(f.name)
+		if errors.Is(err, fs.ErrNotExist) && !f.required {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			opt, err := configOption(levels, e)
+			if err != nil {
+				return nil, err
+			}
+			if opt == nil || opt.tag.Config {
+				continue
+			}
+			if cv, ok := config[opt]; ok && cv.file == e.file {
+				cv.data = append(cv.data, e.data...)
+				cv.list = true
+				continue
+			}
+			cv := e.configValue
+			config[opt] = &cv
+		}
+	}
+	return config, nil
+}
+
+// addConfigFlag adds the flag that Parser.ConfigFlag names to s, unless s
+// already has a flag of that name. Its value isn't stored in the struct.
+func (s *spec) addConfigFlag() error {
+	name := s.parser.ConfigFlag
+	if name == "" || s.long[name] != nil {
+		return nil
+	}
+	var file string
+	return s.addOption(&option{
+		name:  name,
+		path:  "--" + name,
+		field: reflect.StructField{Name: name, Type: reflect.TypeOf(file)},
+		value: reflect.ValueOf(&file).Elem(),
+		tag:   tagData{Description: "read settings from this file", LongFlags: []string{name}, Config: true},
+	})
+}
+
+// configName returns the name of the config file that opt names, and
+// whether it was given on the command line or in the environment, rather
+// than being the default.
+func (p *Parser) configName(opt *option, values map[*option][]string) (string, bool) {
+	if data := values[opt]; len(data) > 0 {
+		return data[len(data)-1], true
+	}
+	if opt.env != "" {
+		if val, ok := p.lookupEnv(opt.env); ok && val != "" {
+			return val, true
+		}
+	}
+	v := reflect.Indirect(opt.value)
+	if !v.IsValid() {
+		return "", false
+	}
+	return v.String(), false
+}
+
+// readConfig reads and parses the config file called name.
+func (p *Parser) readConfig(name string) ([]*configEntry, error) {
+	var data []byte
+	var err error
+	if p.FS != nil {
+		data, err = fs.ReadFile(p.FS, name)
+	} else {
+		data, err = os.ReadFile(name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("args: %w", err)
+	}
+	if strings.EqualFold(path.Ext(name), ".json") {
+		return parseJSONConfig(name, data)
+	}
+	return parseINIConfig(name, data)
+}
+
+// configOption returns the option that e sets, or nil if it belongs to a
+// command that wasn't selected.
+func configOption(levels []*level, e *configEntry) (*option, error) {
+	s, key := levels[0].spec, e.key
+	for i := 1; len(key) > 1 && s.hasCommand(key[0]); i++ {
+		if i == len(levels) || levels[i].spec.path[len(levels[i].spec.path)-1] != key[0] {
+			return nil, nil
+		}
+		s, key = levels[i].spec, key[1:]
+	}
+	name := strings.Join(key, "-")
+	if opt := s.long[name]; opt != nil {
+		return opt, nil
+	}
+	msg := fmt.Sprintf("args: %s: unknown key %s", e.pos(), strings.Join(e.key, "."))
+	var candidates []string
+	for long := range s.long {
+		candidates = append(candidates, long)
+	}
+	if suggestions := closest(name, candidates); len(suggestions) > 0 {
+		msg += ", did you mean " + orList(suggestions) + "?"
+	}
+	return nil, errors.New(msg)
+}
+
+// hasCommand returns true if s has a command called name.
+func (s *spec) hasCommand(name string) bool {
+	for _, cmd := range s.commands {
+		if cmd.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// setFromConfig assigns cv to opt.
+func (p *Parser) setFromConfig(opt *option, cv *configValue) error {
+	data := cv.data
+	if !cv.list && kindOf(opt.field.Type) == reflect.Slice {
+		data = strings.Split(data[0], ",")
+		for i := range data {
+			data[i] = strings.TrimSpace(data[i])
+		}
+	}
+	if err := p.setText(opt, data); err != nil {
+		return fmt.Errorf("args: %s: %s", cv.pos(), strings.TrimPrefix(err.Error(), "args: "))
+	}
+	return nil
+}
+
+// parseJSONConfig parses a JSON config file, which is an object whose
+// values are strings, numbers, booleans, arrays of those, or objects.
+func parseJSONConfig(name string, data []byte) ([]*configEntry, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	line := func(offset int64) int {
+		return bytes.Count(data[:offset], []byte("\n")) + 1
+	}
+	fail := func(err error) ([]*configEntry, error) {
+		offset := dec.InputOffset()
+		var serr *json.SyntaxError
+		if errors.As(err, &serr) {
+			offset = serr.Offset
+		} else if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, fmt.Errorf("args: %s:%d: %s", name, line(offset), err)
+	}
+
+	var entries []*configEntry
+	var object func(path []string) error
+	object = func(prefix []string) error {
+		for dec.More() {
+			tok, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			key := append(append([]string{}, prefix...), tok.(string))
+			e := &configEntry{key: key}
+			e.file, e.line = name, line(dec.InputOffset())
+			if tok, err = dec.Token(); err != nil {
+				return err
+			}
+			switch tok {
+			case json.Delim('{'):
+				if err := object(key); err != nil {
+					return err
+				}
+				continue
+			case json.Delim('['):
+				e.list = true
+				for dec.More() {
+					if tok, err = dec.Token(); err != nil {
+						return err
+					}
+					s, ok := jsonScalar(tok)
+					if !ok {
+						return fmt.Errorf("%s must hold strings, numbers or booleans", strings.Join(key, "."))
+					}
+					e.data = append(e.data, s)
+				}
+				if _, err := dec.Token(); err != nil {
+					return err
+				}
+			case nil:
+				continue
+			default:
+				s, _ := jsonScalar(tok)
+				e.data = []string{s}
+			}
+			entries = append(entries, e)
+		}
+		_, err := dec.Token()
+		return err
+	}
+
+	tok, err := dec.Token()
+	if err != nil {
+		return fail(err)
+	}
+	if tok != json.Delim('{') {
+		return fail(errors.New("config must be a JSON object"))
+	}
+	if err := object(nil); err != nil {
+		return fail(err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return fail(errors.New("unexpected data after the config object"))
+	}
+	return entries, nil
+}
+
+// jsonScalar returns the text of a JSON string, number or boolean.
+func jsonScalar(tok json.Token) (string, bool) {
+	switch v := tok.(type) {
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	case bool:
+		return strconv.FormatBool(v), true
+	}
+	return "", false
+}
+
+// parseINIConfig parses an INI config file. Lines are key = value pairs,
+// [section] headers, or comments beginning with ; or #. Section names can
+// be nested with dots, as in [db.tls]. Values can be quoted.
+func parseINIConfig(name string, data []byte) ([]*configEntry, error) {
+	var entries []*configEntry
+	var section []string
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == ';' || line[0] == '#' {
+			continue
+		}
+		if line[0] == '[' {
+			if !strings.HasSuffix(line, "]") || strings.TrimSpace(line[1:len(line)-1]) == "" {
+				return nil, fmt.Errorf("args: %s:%d: bad section %s", name, i+1, line)
+			}
+			section = strings.Split(line[1:len(line)-1], ".")
+			for j := range section {
+				section[j] = strings.TrimSpace(section[j])
+			}
+			continue
+		}
+		eq := strings.Index(line, "=")
+		if eq < 0 {
+			return nil, fmt.Errorf("args: %s:%d: expected key = value", name, i+1)
+		}
+		key := strings.TrimSpace(line[:eq])
+		value := strings.TrimSpace(line[eq+1:])
+		if key == "" {
+			return nil, fmt.Errorf("args: %s:%d: missing key", name, i+1)
+		}
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			if value[0] == '"' {
+				v, err := strconv.Unquote(value)
+				if err != nil {
+					return nil, fmt.Errorf("args: %s:%d: bad value %s", name, i+1, value)
+				}
+				value = v
+			} else {
+				value = value[1 : len(value)-1]
+			}
+		}
+		e := &configEntry{key: append(append([]string{}, section...), key)}
+		e.data, e.file, e.line = []string{value}, name, i+1
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
@@ -0,0 +1,158 @@
+package args
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+type ConfigTest struct {
+	Config  string   `args:"config file,config"`
+	Verbose bool     `args:"be chatty"`
+	Name    string   `args:"a name,env=NAME"`
+	Port    int      `args:"a port"`
+	Tags    []string `args:"tags"`
+	DB      DBOptions
+}
+
+func TestConfig(t *testing.T) {
+	fsys := fstest.MapFS{
+		"base.json": {Data: []byte(`{
+	"verbose": true,
+	"name": "from base",
+	"port": 80,
+	"tags": ["a", "b"],
+	"db": {"host": "db.example.com", "port": 5432}
+}`)},
+		"app.ini": {Data: []byte(`
+; overrides base.json
+name = "from ini"
+tags = c, d
+
+[db]
+port = 6543
+`)},
+	}
+	p := &Parser{
+		FS:          fsys,
+		ConfigFiles: []string{"base.json", "missing.json"},
+		LookupEnv:   env(map[string]string{"NAME": "from env"}),
+	}
+
+	got := ConfigTest{Config: "app.ini", Port: 1}
+	if err := p.ParseArgs(&got, []string{"--port", "8080"}); err != nil {
+		t.Fatal(err)
+	}
+	want := ConfigTest{
+		Config:  "app.ini",
+		Verbose: true,
+		Name:    "from env",
+		Port:    8080,
+		Tags:    []string{"c", "d"},
+		DB:      DBOptions{Host: "db.example.com", Port: 6543},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("bad data: got %+v, want %+v", got, want)
+	}
+
+	// A config file that is named on the command line must exist.
+	if err := p.ParseArgs(&ConfigTest{}, []string{"--config", "nope.ini"}); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestConfigErrors(t *testing.T) {
+	tests := []struct {
+		name, data, err string
+	}{
+		{"a.ini", "port = 1\n\nprot = 2\n", "a.ini:3: unknown key prot, did you mean port?"},
+		{"a.ini", "[db]\nhots = x\n", "a.ini:2: unknown key db.hots, did you mean db-host?"},
+		{"a.ini", "port = 1\nport: 2\n", "a.ini:2: expected key = value"},
+		{"a.ini", "\nport = eighty\n", "a.ini:2: "},
+		{"a.json", "{\n\"port\": 1,\n\"verbose\": \"maybe\"\n}", "a.json:3: option verbose"},
+		{"a.json", "{\n\"port\": 1,\n\"db\": {\"nmae\": 1}}", "a.json:3: unknown key db.nmae"},
+		{"a.json", "{\n\"port\": 1\n\"name\": 2}", "a.json:3: "},
+		{"a.json", "[1, 2]", "a.json:1: config must be a JSON object"},
+	}
+	for _, test := range tests {
+		p := &Parser{
+			FS:          fstest.MapFS{test.name: {Data: []byte(test.data)}},
+			ConfigFiles: []string{test.name},
+		}
+		err := p.ParseArgs(&ConfigTest{}, nil)
+		if err == nil {
+			t.Fatalf("%q: expected error", test.data)
+		}
+		if !strings.Contains(err.Error(), test.err) {
+			t.Fatalf("%q: bad error: %s", test.data, err)
+		}
+	}
+}
+
+func TestConfigCommands(t *testing.T) {
+	p := &Parser{
+		FS: fstest.MapFS{"git.ini": {Data: []byte(`
+verbose = true
+
+[clone]
+depth = 1
+
+[remote.add]
+fetch = true
+`)}},
+		ConfigFiles: []string{"git.ini"},
+	}
+	var got gitArgs
+	if err := p.ParseArgs(&got, []string{"clone", "x"}); err != nil {
+		t.Fatal(err)
+	}
+	if !got.Verbose || got.Clone == nil || got.Clone.Depth != 1 {
+		t.Fatalf("bad data: %+v", got)
+	}
+}
+
+func TestConfigFlag(t *testing.T) {
+	type Test struct {
+		Name string `args:"a name"`
+		Port int    `args:"a port"`
+	}
+	fsys := fstest.MapFS{
+		"app.ini":   {Data: []byte("name = from ini\nport = 80\n")},
+		"other.ini": {Data: []byte("name = from other\n")},
+	}
+	p := &Parser{FS: fsys, ConfigFlag: "config", ConfigFiles: []string{"app.ini"}}
+
+	var got Test
+	if err := p.ParseArgs(&got, []string{"--config", "other.ini", "--port", "8080"}); err != nil {
+		t.Fatal(err)
+	}
+	if want := (Test{Name: "from other", Port: 8080}); !reflect.DeepEqual(got, want) {
+		t.Fatalf("bad data: got %+v, want %+v", got, want)
+	}
+	if err := p.ParseArgs(&Test{}, []string{"--config", "nope.ini"}); err == nil {
+		t.Fatal("expected error")
+	}
+
+	// Without the flag, there's no such flag.
+	if err := ParseArgs(&Test{}, []string{"--config", "other.ini"}); err == nil {
+		t.Fatal("expected error")
+	}
+
+	// A flag of the same name in the struct takes its place.
+	got2 := ConfigTest{}
+	if err := p.ParseArgs(&got2, []string{"--config", "other.ini"}); err != nil {
+		t.Fatal(err)
+	}
+	if got2.Config != "other.ini" || got2.Name != "from other" {
+		t.Fatalf("bad data: %+v", got2)
+	}
+
+	var buf strings.Builder
+	if err := p.usage(&buf, Test{}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "--config\t(default: \"\")\tread settings from this file"; !strings.Contains(buf.String(), want) {
+		t.Fatalf("usage is missing %q:\n%s", want, buf.String())
+	}
+}
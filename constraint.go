@@ -0,0 +1,166 @@
+package args
+
+import (
+	"fmt"
+	"strings"
+)
+
+// constraint limits which flags of a set can be given together. Flags
+// join the set by naming it with the xor, oneof or anyof tag key.
+type constraint struct {
+	kind string // xor: at most one, oneof: exactly one, anyof: at least one
+	name string
+	opts []*option
+}
+
+// addConstraints adds opt to the constraints that its tag names.
+func (s *spec) addConstraints(opt *option) error {
+	for _, set := range [][2]string{{"xor", opt.tag.Xor}, {"oneof", opt.tag.OneOf}, {"anyof", opt.tag.AnyOf}} {
+		kind, name := set[0], set[1]
+		if name == "" {
+			continue
+		}
+		var c *constraint
+		for _, other := range s.constraints {
+			if other.name == name {
+				c = other
+			}
+		}
+		if c == nil {
+			c = &constraint{kind: kind, name: name}
+			s.constraints = append(s.constraints, c)
+		} else if c.kind != kind {
+			return fmt.Errorf(
+				"args: bad tag on field %s: %s is a %s set, not a %s set", opt.path, name, c.kind, kind)
+		}
+		c.opts = append(c.opts, opt)
+	}
+	return nil
+}
+
+// resolveRequires finds the flags that each flag requires. A name is
+// looked up in the group of the flag first, so a flag in the group tls can
+// require key rather than tls-key.
+func (s *spec) resolveRequires() error {
+	for _, opt := range s.flags {
+		for _, name := range opt.tag.Requires {
+			name = strings.TrimPrefix(name, "--")
+			var other *option
+			if opt.group != nil {
+				other = s.long[opt.group.prefix+name]
+			}
+			if other == nil {
+				other = s.long[name]
+			}
+			if other == nil || other == opt {
+				return fmt.Errorf("args: bad tag on field %s: it requires unknown flag --%s", opt.path, name)
+			}
+			opt.requires = append(opt.requires, other)
+		}
+	}
+	return nil
+}
+
+// shadow finds the flags of xor and oneof sets that are only set by a
+// lower source than another flag of the set, such as a config file when
+// the other flag is on the command line, and marks them in st as
+// shadowed. The flag from the higher source replaces them, rather than
+// clashing with them.
+func (p *Parser) shadow(levels []*level, st *parseState) {
+	rank := func(opt *option) int {
+		if _, ok := st.values[opt]; ok {
+			return 3
+		}
+		if opt.env != "" {
+			if val, ok := p.lookupEnv(opt.env); ok && val != "" {
+				return 2
+			}
+		}
+		if _, ok := st.config[opt]; ok {
+			return 1
+		}
+		return 0
+	}
+	for _, l := range levels {
+		for _, c := range l.spec.constraints {
+			if c.kind == "anyof" {
+				continue
+			}
+			top := 0
+			for _, opt := range c.opts {
+				if r := rank(opt); r > top {
+					top = r
+				}
+			}
+			for _, opt := range c.opts {
+				if r := rank(opt); r > 0 && r < top {
+					st.shadowed[opt] = true
+				}
+			}
+		}
+	}
+}
+
+// checkConstraints returns an error for each constraint of s that the
+// options in set break.
+func (s *spec) checkConstraints(set map[*option]bool) []error {
+	var errs []error
+	fail := func(format string, a ...interface{}) {
+		errs = append(errs, fmt.Errorf("%s: %s", s.parser.name(), fmt.Sprintf(format, a...)))
+	}
+	for _, c := range s.constraints {
+		var given []string
+		for _, opt := range c.opts {
+			if set[opt] {
+				given = append(given, "--"+opt.name)
+			}
+		}
+		switch {
+		case len(given) > 1 && c.kind != "anyof":
+			fail("%s can't be given together", andList(given))
+		case len(given) == 0 && c.kind == "oneof":
+			fail("one of %s is required", orList(c.flagNames()))
+		case len(given) == 0 && c.kind == "anyof":
+			fail("at least one of %s is required", orList(c.flagNames()))
+		}
+	}
+	for _, opt := range s.flags {
+		if !set[opt] {
+			continue
+		}
+		for _, other := range opt.requires {
+			if !set[other] {
+				fail("--%s requires --%s", opt.name, other.name)
+			}
+		}
+	}
+	return errs
+}
+
+func (c *constraint) flagNames() []string {
+	names := make([]string, len(c.opts))
+	for i, opt := range c.opts {
+		names[i] = "--" + opt.name
+	}
+	return names
+}
+
+// synopsis shows the constraint in the notation of docopt: [a | b] for
+// at most one, (a | b) for exactly one, and (a | b)... for at least one.
+func (c *constraint) synopsis() string {
+	alts := strings.Join(c.flagNames(), " | ")
+	switch c.kind {
+	case "xor":
+		return "[" + alts + "]"
+	case "oneof":
+		return "(" + alts + ")"
+	}
+	return "(" + alts + ")..."
+}
+
+func andList(items []string) string {
+	if len(items) == 1 {
+		return items[0]
+	}
+	return strings.Join(items[:len(items)-1], ", ") + " and " + items[len(items)-1]
+}
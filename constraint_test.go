@@ -0,0 +1,113 @@
+package args
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+type InputArgs struct {
+	File  string `args:"help='read a file' oneof=source"`
+	URL   string `args:"help='read a url' oneof=source"`
+	Stdin bool   `args:"help='read stdin' oneof=source"`
+	User  string `args:"help='a user' anyof=owner"`
+	Group string `args:"help='a group' anyof=owner"`
+	Quiet bool   `args:"help='say less' xor=noise"`
+	Loud  bool   `args:"help='say more' xor=noise"`
+	TLS   struct {
+		Cert string `args:"help='certificate file' requires=key"`
+		Key  string `args:"help='key file'"`
+	}
+}
+
+func TestConstraints(t *testing.T) {
+	good := [][]string{
+		{"--file", "x", "--user", "u"},
+		{"--stdin", "--user", "u", "--group", "g", "--quiet"},
+		{"--url", "u", "--group", "g", "--tls-cert", "c", "--tls-key", "k"},
+		{"--url", "u", "--group", "g", "--tls-key", "k"},
+	}
+	for _, args := range good {
+		if err := ParseArgs(&InputArgs{}, args); err != nil {
+			t.Fatalf("%q: %s", args, err)
+		}
+	}
+
+	p := &Parser{Name: "prog"}
+	tests := []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"--user", "u"}, "prog: one of --file, --url or --stdin is required"},
+		{[]string{"--file", "x", "--stdin", "--user", "u"}, "prog: --file and --stdin can't be given together"},
+		{[]string{"--file", "x"}, "prog: at least one of --user or --group is required"},
+		{[]string{"--file", "x", "--user", "u", "--quiet", "--loud"}, "prog: --quiet and --loud can't be given together"},
+		{[]string{"--file", "x", "--user", "u", "--tls-cert", "c"}, "prog: --tls-cert requires --tls-key"},
+		{[]string{"--url", "u", "--file", "x", "--tls-cert", "c"}, "prog: --file and --url can't be given together\n" +
+			"prog: at least one of --user or --group is required\n" +
+			"prog: --tls-cert requires --tls-key"},
+	}
+	for _, test := range tests {
+		err := p.ParseArgs(&InputArgs{}, test.args)
+		var verr *ValidationError
+		if !errors.As(err, &verr) {
+			t.Fatalf("%q: expected a validation error, got %v", test.args, err)
+		}
+		if err.Error() != test.err {
+			t.Fatalf("%q: bad error: got\n%s\nwant\n%s", test.args, err, test.err)
+		}
+	}
+
+	var buf bytes.Buffer
+	p.Stdout = &buf
+	if err := p.Usage(InputArgs{}); err != nil {
+		t.Fatal(err)
+	}
+	usage := buf.String()
+	if want := "usage: prog [options] (--file | --url | --stdin) (--user | --group)... [--quiet | --loud]\n"; !strings.HasPrefix(usage, want) {
+		t.Fatalf("bad synopsis: got\n%s\nwant\n%s", usage, want)
+	}
+	if want := "certificate file (requires --tls-key)"; !strings.Contains(usage, want) {
+		t.Fatalf("usage is missing %q:\n%s", want, usage)
+	}
+}
+
+func TestConstraintsAndSources(t *testing.T) {
+	type Test struct {
+		File string `args:"help='read a file' xor=source env=FILE"`
+		URL  string `args:"help='read a url' xor=source"`
+		Dir  string `args:"help='read a dir' xor=source env=DIR"`
+	}
+	fsys := fstest.MapFS{"app.ini": {Data: []byte("url = http://x\n")}}
+	tests := []struct {
+		args []string
+		env  map[string]string
+		want Test
+		err  string
+	}{
+		{args: nil, want: Test{URL: "http://x"}},
+		{args: []string{"--file", "f"}, want: Test{File: "f"}},
+		{args: nil, env: map[string]string{"DIR": "d"}, want: Test{Dir: "d"}},
+		{args: []string{"--file", "f"}, env: map[string]string{"DIR": "d"}, want: Test{File: "f"}},
+		{args: nil, env: map[string]string{"DIR": "d", "FILE": "f"}, err: "prog: --file and --dir can't be given together"},
+	}
+	for _, test := range tests {
+		p := &Parser{Name: "prog", FS: fsys, ConfigFiles: []string{"app.ini"}, LookupEnv: env(test.env)}
+		var got Test
+		err := p.ParseArgs(&got, test.args)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Fatalf("%q %v: bad error: got %v, want %s", test.args, test.env, err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%q %v: %s", test.args, test.env, err)
+		}
+		if got != test.want {
+			t.Fatalf("%q %v: bad data: got %+v, want %+v", test.args, test.env, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package args
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// setCount sets the counter opt from data, in which each empty value is one
+// more use of the switch, as lexed from -v or -vvv, and any other value
+// sets the count, as in --verbose=3. The count stops at the cap of opt, but
+// a value above the cap is an error.
+func (opt *option) setCount(data []string) error {
+	n := 0
+	for _, d := range data {
+		if d == "" {
+			n++
+			continue
+		}
+		c, err := strconv.Atoi(d)
+		if err != nil || c < 0 {
+			return fmt.Errorf("args: option %s: bad count %q", opt.name, d)
+		}
+		if opt.tag.CountCap > 0 && c > opt.tag.CountCap {
+			return fmt.Errorf("args: option %s: %d is more than the cap of %d", opt.name, c, opt.tag.CountCap)
+		}
+		n = c
+	}
+	if opt.tag.CountCap > 0 && n > opt.tag.CountCap {
+		n = opt.tag.CountCap
+	}
+	return setField(opt.value, opt.name, []string{strconv.Itoa(n)})
+}
@@ -0,0 +1,62 @@
+package args
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCount(t *testing.T) {
+	type Test struct {
+		Verbose int   `args:"help='more output' short=v count env=VERBOSE"`
+		Quiet   *uint `args:"help='less output' short=q count=2"`
+		Force   bool  `args:"force,-f"`
+	}
+	tests := []struct {
+		args    []string
+		env     map[string]string
+		verbose int
+		quiet   uint
+	}{
+		{args: nil, verbose: 0},
+		{args: []string{"-v"}, verbose: 1},
+		{args: []string{"-v", "-v", "--verbose"}, verbose: 3},
+		{args: []string{"-vvv"}, verbose: 3},
+		{args: []string{"-vfv"}, verbose: 2},
+		{args: []string{"--verbose=5"}, verbose: 5},
+		{args: []string{"--verbose=2", "-v"}, verbose: 3},
+		{args: []string{"-qqqq"}, quiet: 2},
+		{args: nil, env: map[string]string{"VERBOSE": "4"}, verbose: 4},
+		{args: []string{"-v"}, env: map[string]string{"VERBOSE": "4"}, verbose: 1},
+	}
+	for _, test := range tests {
+		var got Test
+		p := &Parser{LookupEnv: env(test.env)}
+		if err := p.ParseArgs(&got, test.args); err != nil {
+			t.Fatalf("%q: %s", test.args, err)
+		}
+		if got.Verbose != test.verbose {
+			t.Fatalf("%q: bad verbose: got %d, want %d", test.args, got.Verbose, test.verbose)
+		}
+		if (got.Quiet == nil && test.quiet != 0) || (got.Quiet != nil && *got.Quiet != test.quiet) {
+			t.Fatalf("%q: bad quiet: got %v, want %d", test.args, got.Quiet, test.quiet)
+		}
+	}
+
+	for _, test := range []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"--verbose=x"}, `option verbose: bad count "x"`},
+		{[]string{"--verbose=-1"}, `option verbose: bad count "-1"`},
+		{[]string{"--quiet=3"}, `option quiet: 3 is more than the cap of 2`},
+		{[]string{"-v3"}, `unknown flag`},
+	} {
+		err := ParseArgs(&Test{}, test.args)
+		if err == nil {
+			t.Fatalf("%q: expected error", test.args)
+		}
+		if !strings.Contains(err.Error(), test.err) {
+			t.Fatalf("%q: bad error: %s", test.args, err)
+		}
+	}
+}
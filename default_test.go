@@ -0,0 +1,85 @@
+package args
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTagDefaults(t *testing.T) {
+	type Test struct {
+		Name    string        `args:"a name,default=anonymous"`
+		Port    int           `args:"a port,default=8080,env=PORT"`
+		Ratio   *float64      `args:"a ratio,default=0.5"`
+		Tags    []string      `args:"help=tags default='a,b'"`
+		Color   bool          `args:"use color,default=true"`
+		Timeout time.Duration `args:"a timeout,default=30s"`
+		Format  string        `args:"help=format choices=json|text default=text"`
+		File    string        `args:"help='a file' pos default=-"`
+	}
+
+	p := &Parser{LookupEnv: env(map[string]string{"PORT": "9090"})}
+	got := Test{}
+	if err := p.ParseArgs(&got, nil); err != nil {
+		t.Fatal(err)
+	}
+	ratio := 0.5
+	want := Test{
+		Name:    "anonymous",
+		Port:    9090,
+		Ratio:   &ratio,
+		Tags:    []string{"a", "b"},
+		Color:   true,
+		Timeout: 30 * time.Second,
+		Format:  "text",
+		File:    "-",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("bad data: got %+v, want %+v", got, want)
+	}
+
+	got = Test{}
+	testArgs := []string{"--name", "x", "--ratio", "0.25", "--tags", "c", "--format", "json", "f.txt"}
+	if err := p.ParseArgs(&got, testArgs); err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "x" || *got.Ratio != 0.25 || !reflect.DeepEqual(got.Tags, []string{"c"}) || got.Format != "json" || got.File != "f.txt" {
+		t.Fatalf("bad data: %+v", got)
+	}
+
+	// Defaults don't count as set.
+	res, err := p.ParseResult(&Test{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.IsSet("Name") || !res.IsSet("Port") {
+		t.Fatalf("bad result: %+v", res.Settings)
+	}
+
+	// Values that the caller put in the struct beforehand are kept.
+	got = Test{Name: "bob", Tags: []string{"c"}, File: "f.txt"}
+	if err := p.ParseArgs(&got, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "bob" || !reflect.DeepEqual(got.Tags, []string{"c"}) || got.File != "f.txt" || got.Format != "text" {
+		t.Fatalf("bad data: %+v", got)
+	}
+
+	var buf bytes.Buffer
+	if err := Usage(&buf, Test{Port: 80}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"--name\t(default: \"anonymous\")",
+		"--ratio\t(default: 0.5)",
+		"--tags\t(default: a,b)",
+		"--port\t(default: 80)",
+		"usage: " + (&Parser{}).name() + " [options] [FILE]\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Fatalf("usage is missing %q:\n%s", want, buf.String())
+		}
+	}
+}
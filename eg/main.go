@@ -8,25 +8,20 @@ import (
 
 // Args is the spec for the arguments we want our program to accept.
 type Args struct {
-	Foo int     `args:"this is a foo,-f"` // Foo has a short flag, -f
-	Bar float32 `args:"this is a bar,r"`  // Bar is required
-	Baz string  `args:"a baz!,r"`         // Baz is required too
+	Foo int     `args:"this is a foo,-f,default=5"` // Foo has a short flag, -f, and defaults to 5
+	Bar float32 `args:"this is a bar,r"`            // Bar is required
+	Baz string  `args:"a baz!,r"`                   // Baz is required too
 }
 
-// With args, users set defaults by putting the default they want in the struct.
-// If a value type is used, the default will be the zero value.
-// If a pointer type is used, there will be no default.
-var defaultArgs = Args{Foo: 5}
-
 func main() {
-	a := defaultArgs // copy defaultArgs
+	var a Args
 
 	// Try to parse args into a. Internally, args reads os.Args.
 	if err := args.Parse(&a); err != nil {
 		// Will print an error if an argument is malformed.
 		fmt.Fprintf(os.Stderr, "%s\n", err)
-		// Print the usage
-		if err := args.Usage(os.Stderr, defaultArgs); err != nil {
+		// Print the usage, which shows the defaults from the tags.
+		if err := args.Usage(os.Stderr, Args{}); err != nil {
 			fmt.Fprintf(os.Stderr, "%s\n", err)
 		}
 		return
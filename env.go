@@ -0,0 +1,67 @@
+package args
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// setFromEnv assigns the value of opt's environment variable to opt. It
+// returns false if opt has no variable, or it is unset or empty. The value
+// is converted the same way as a flag's, except that booleans are parsed
+// with parseBool, and slices are split on commas.
+func (p *Parser) setFromEnv(opt *option) (bool, error) {
+	if opt.env == "" {
+		return false, nil
+	}
+	val, ok := p.lookupEnv(opt.env)
+	if !ok || val == "" {
+		return false, nil
+	}
+	var data []string
+	if kindOf(opt.field.Type) == reflect.Slice {
+		data = strings.Split(val, ",")
+	} else {
+		data = []string{val}
+	}
+	if err := p.setText(opt, data); err != nil {
+		return false, fmt.Errorf("%s (from $%s)", err, opt.env)
+	}
+	return true, nil
+}
+
+// setText assigns data, which came from somewhere other than the command
+// line, to opt. It is converted the same way as a flag's value, except
+// that a boolean is parsed with parseBool rather than switched on.
+func (p *Parser) setText(opt *option, data []string) error {
+	if kindOf(opt.field.Type) != reflect.Bool {
+		return p.setOption(opt, data)
+	}
+	if len(data) != 1 {
+		return fmt.Errorf("args: option %s takes one value", opt.name)
+	}
+	b, err := parseBool(data[0])
+	if err != nil {
+		return fmt.Errorf("args: option %s: %s", opt.name, err)
+	}
+	v := opt.value
+	if v.Kind() == reflect.Ptr {
+		v.Set(reflect.New(v.Type().Elem()))
+		v = v.Elem()
+	}
+	v.SetBool(b)
+	return nil
+}
+
+func (p *Parser) lookupEnv(key string) (string, bool) {
+	if p.LookupEnv != nil {
+		return p.LookupEnv(key)
+	}
+	return os.LookupEnv(key)
+}
+
+// upperSnake maps a flag name such as max-conns to MAX_CONNS.
+func upperSnake(name string) string {
+	return strings.ToUpper(strings.Replace(name, "-", "_", -1))
+}
@@ -0,0 +1,129 @@
+package args
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// env returns a LookupEnv function for the variables in m.
+func env(m map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		v, ok := m[key]
+		return v, ok
+	}
+}
+
+func TestEnv(t *testing.T) {
+	type Test struct {
+		Size    int      `args:"size in bytes,env=SIZE"`
+		Name    string   `args:"help='a name' env=NAME"`
+		Tags    []string `args:"tags,env"`
+		Ratio   *float64 `args:"a ratio,env=RATIO"`
+		Color   bool     `args:"use color,env=COLOR"`
+		Verbose *bool    `args:"be chatty,env=VERBOSE"`
+		Host    string   `args:"a host,env=HOST,r"`
+		Port    int      `args:"a port"`
+	}
+
+	p := &Parser{LookupEnv: env(map[string]string{
+		"SIZE":    "10",
+		"NAME":    "from env",
+		"TAGS":    "a,b,c",
+		"RATIO":   "0.5",
+		"COLOR":   "false",
+		"VERBOSE": "false",
+		"HOST":    "",
+		"PORT":    "80",
+	})}
+
+	got := Test{Size: 1, Color: true, Port: 8080}
+	if err := p.ParseArgs(&got, []string{"--name", "from flag", "--host", "example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	ratio, verbose := 0.5, false
+	want := Test{
+		Size:    10,
+		Name:    "from flag",
+		Tags:    []string{"a", "b", "c"},
+		Ratio:   &ratio,
+		Color:   false,
+		Verbose: &verbose,
+		Host:    "example.com",
+		Port:    8080,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("bad data: got %+v, want %+v", got, want)
+	}
+
+	// An empty variable doesn't satisfy a required flag
+	got = Test{}
+	if err := p.ParseArgs(&got, nil); err == nil {
+		t.Fatal("expected error")
+	}
+
+	p.LookupEnv = env(map[string]string{"HOST": "x", "SIZE": "ten"})
+	got = Test{}
+	err := p.ParseArgs(&got, nil)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if !strings.Contains(err.Error(), "$SIZE") {
+		t.Fatalf("bad error: %s", err)
+	}
+
+	type BadEnv struct {
+		Name string `args:",pos,env=NAME"`
+	}
+	if err := p.ParseArgs(&BadEnv{}, nil); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestEnvPrefix(t *testing.T) {
+	type Test struct {
+		MaxConns int       `args:"connection limit"`
+		Name     string    `args:"a name,env=NAME"`
+		DB       DBOptions `args:"help='Database options'"`
+		Clone    *cloneCmd `args:"help='clone a repository' cmd"`
+	}
+
+	p := &Parser{EnvPrefix: "MYAPP_", LookupEnv: env(map[string]string{
+		"MYAPP_MAX_CONNS": "5",
+		"NAME":            "foo",
+		"MYAPP_NAME":      "bar",
+		"MYAPP_DB_HOST":   "db",
+		"MYAPP_DEPTH":     "3",
+	})}
+
+	var got Test
+	if err := p.ParseArgs(&got, []string{"clone", "x"}); err != nil {
+		t.Fatal(err)
+	}
+	want := Test{
+		MaxConns: 5,
+		Name:     "foo",
+		DB:       DBOptions{Host: "db"},
+		Clone:    &cloneCmd{Depth: 3, URL: "x"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("bad data: got %+v, want %+v", got, want)
+	}
+
+	var buf bytes.Buffer
+	p.Name = "prog"
+	p.Stdout = &buf
+	if err := p.Usage(Test{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		" \t--max-conns\t(default: 0)\tconnection limit [$MYAPP_MAX_CONNS]",
+		" \t--name\t(default: \"\")\ta name [$NAME]",
+		" \t--db-tls-cert\t(default: \"\")\tcertificate file [$MYAPP_DB_TLS_CERT]",
+	} {
+		if !strings.Contains(buf.String(), line+"\n") {
+			t.Errorf("usage is missing %q:\n%s", line, buf.String())
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package args
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestImplicitValues(t *testing.T) {
+	type Test struct {
+		Color   string        `args:"help='when to use color' choices=auto|always|never default=auto implicit=always"`
+		Backup  *string       `args:"help='make backups' short=b implicit=existing"`
+		Wait    time.Duration `args:"help='wait for it' implicit=1m"`
+		Verbose bool          `args:"help='be chatty' short=v"`
+		Files   []string      `args:"help=files pos"`
+	}
+	wait := time.Minute
+	tests := []struct {
+		args   []string
+		color  string
+		backup string
+		wait   time.Duration
+		files  []string
+	}{
+		{args: nil, color: "auto"},
+		{args: []string{"--color"}, color: "always"},
+		{args: []string{"--color=never"}, color: "never"},
+		{args: []string{"--color", "a.txt"}, color: "always", files: []string{"a.txt"}},
+		{args: []string{"--backup", "a.txt"}, color: "auto", backup: "existing", files: []string{"a.txt"}},
+		{args: []string{"-vb", "a.txt"}, color: "auto", backup: "existing", files: []string{"a.txt"}},
+		{args: []string{"--backup=numbered", "--wait"}, color: "auto", backup: "numbered", wait: wait},
+		{args: []string{"--wait=-5s"}, color: "auto", wait: -5 * time.Second},
+	}
+	for _, test := range tests {
+		var got Test
+		if err := ParseArgs(&got, test.args); err != nil {
+			t.Fatalf("%q: %s", test.args, err)
+		}
+		var backup string
+		if got.Backup != nil {
+			backup = *got.Backup
+		}
+		if got.Color != test.color || backup != test.backup || got.Wait != test.wait ||
+			strings.Join(got.Files, " ") != strings.Join(test.files, " ") {
+			t.Fatalf("%q: bad data: %+v", test.args, got)
+		}
+	}
+
+	if err := ParseArgs(&Test{}, []string{"--color=sometimes"}); err == nil {
+		t.Fatal("expected error")
+	}
+
+	var buf bytes.Buffer
+	if err := Usage(&buf, Test{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"\t--color[=COLOR]\t(default: \"auto\")\twhen to use color {auto|always|never} (implicit: always)",
+		" -b,\t--backup[=BACKUP]\t\tmake backups (implicit: existing)",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Fatalf("usage is missing %q:\n%s", want, buf.String())
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package args
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	ipType       = reflect.TypeOf(net.IP{})
+	ipNetType    = reflect.TypeOf(net.IPNet{})
+	urlType      = reflect.TypeOf(url.URL{})
+	hostPortType = reflect.TypeOf(HostPort{})
+	addrType     = reflect.TypeOf(netip.Addr{})
+	prefixType   = reflect.TypeOf(netip.Prefix{})
+	addrPortType = reflect.TypeOf(netip.AddrPort{})
+)
+
+// HostPort is a host and a port, as in example.com:443 or [::1]:8080. The
+// port tag key gives the port of hosts that are given without one, as in
+// port=443.
+type HostPort struct {
+	Host string
+	Port int
+}
+
+func (hp HostPort) String() string {
+	return net.JoinHostPort(hp.Host, strconv.Itoa(hp.Port))
+}
+
+func parseHostPort(s string) (HostPort, error) {
+	host, port, err := net.SplitHostPort(s)
+	if err != nil {
+		return HostPort{}, err
+	}
+	n, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return HostPort{}, fmt.Errorf("bad port in %q", s)
+	}
+	return HostPort{Host: host, Port: int(n)}, nil
+}
+
+// hasPorts is true if t holds hosts and ports.
+func hasPorts(t reflect.Type) bool {
+	t = baseType(t)
+	return t == hostPortType || t == addrPortType
+}
+
+// hasAddrs is true if t holds IP addresses.
+func hasAddrs(t reflect.Type) bool {
+	switch baseType(t) {
+	case ipType, ipNetType, addrType, prefixType, addrPortType, hostPortType:
+		return true
+	}
+	return false
+}
+
+// hasURLs is true if t holds URLs.
+func hasURLs(t reflect.Type) bool {
+	return baseType(t) == urlType
+}
+
+// checkNet fills in the default port of the network addresses in data,
+// and makes sure that they are of the family and scheme that the tag of
+// opt asks for. It returns the filled in data.
+func (opt *option) checkNet(data []string) ([]string, error) {
+	td := opt.tag
+	if td.Port == "" && td.Family == "" && len(td.Schemes) == 0 {
+		return data, nil
+	}
+	result := make([]string, len(data))
+	for i, s := range data {
+		if td.Port != "" {
+			if _, _, err := net.SplitHostPort(s); err != nil {
+				s = net.JoinHostPort(strings.Trim(s, "[]"), td.Port)
+			}
+		}
+		if td.Family != "" && !inFamily(s, td.Family) {
+			return nil, fmt.Errorf("args: option %s: %q is not an %s address", opt.name, s, td.Family)
+		}
+		if len(td.Schemes) > 0 {
+			// Malformed URLs are left to be reported when they are set.
+			if u, err := url.Parse(s); err == nil && !hasScheme(td.Schemes, u.Scheme) {
+				return nil, fmt.Errorf(
+					"args: option %s: %q must have the scheme %s", opt.name, s, orList(td.Schemes))
+			}
+		}
+		result[i] = s
+	}
+	return result, nil
+}
+
+// inFamily is true if the address, prefix or host and port in s is in the
+// family ipv4 or ipv6. Host names and malformed addresses are let through,
+// to be reported when they are set.
+func inFamily(s, family string) bool {
+	host := s
+	if h, _, err := net.SplitHostPort(s); err == nil {
+		host = h
+	} else if i := strings.IndexByte(s, '/'); i >= 0 {
+		host = s[:i]
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return true
+	}
+	return addr.Unmap().Is4() == (family == "ipv4")
+}
+
+func hasScheme(schemes []string, scheme string) bool {
+	for _, s := range schemes {
+		if strings.EqualFold(s, scheme) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package args
+
+import (
+	"bytes"
+	"net"
+	"net/netip"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNetValues(t *testing.T) {
+	type Test struct {
+		Bind     net.IP           `args:"bind address"`
+		Allow    []net.IPNet      `args:"allowed networks"`
+		Peer     netip.Addr       `args:"peer address"`
+		Prefixes []netip.Prefix   `args:"prefixes"`
+		DNS      netip.AddrPort   `args:"help='dns server' port=53"`
+		Upstream *url.URL         `args:"help='upstream url' scheme=http scheme=https"`
+		Mirrors  []*url.URL       `args:"mirrors"`
+		Listen   HostPort         `args:"help='listen address' port=8080"`
+		Backends []HostPort       `args:"backends"`
+		Via      *netip.AddrPort  `args:"help='proxy' family=ipv6"`
+		Extra    []netip.AddrPort `args:"extra"`
+	}
+	var got Test
+	testArgs := []string{
+		"--bind", "127.0.0.1",
+		"--allow", "10.0.0.0/8", "fd00::/8",
+		"--peer", "::1",
+		"--prefixes", "192.168.0.0/16",
+		"--dns", "1.1.1.1",
+		"--upstream", "https://example.com/api",
+		"--mirrors", "ftp://a.example.com", "http://b.example.com",
+		"--listen", "localhost",
+		"--backends", "a:80", "[::1]:81",
+		"--via", "[::1]:3128",
+	}
+	if err := ParseArgs(&got, testArgs); err != nil {
+		t.Fatal(err)
+	}
+	_, n1, _ := net.ParseCIDR("10.0.0.0/8")
+	_, n2, _ := net.ParseCIDR("fd00::/8")
+	upstream, _ := url.Parse("https://example.com/api")
+	m1, _ := url.Parse("ftp://a.example.com")
+	m2, _ := url.Parse("http://b.example.com")
+	via := netip.MustParseAddrPort("[::1]:3128")
+	want := Test{
+		Bind:     net.ParseIP("127.0.0.1"),
+		Allow:    []net.IPNet{*n1, *n2},
+		Peer:     netip.MustParseAddr("::1"),
+		Prefixes: []netip.Prefix{netip.MustParsePrefix("192.168.0.0/16")},
+		DNS:      netip.MustParseAddrPort("1.1.1.1:53"),
+		Upstream: upstream,
+		Mirrors:  []*url.URL{m1, m2},
+		Listen:   HostPort{Host: "localhost", Port: 8080},
+		Backends: []HostPort{{"a", 80}, {"::1", 81}},
+		Via:      &via,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("bad data: got %+v, want %+v", got, want)
+	}
+
+	tests := []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"--bind", "localhost"}, `option bind: invalid IP address: localhost`},
+		{[]string{"--allow", "10.0.0.1"}, `option allow: invalid CIDR address: 10.0.0.1`},
+		{[]string{"--backends", "a"}, `option backends: address a: missing port in address`},
+		{[]string{"--listen", "a:http"}, `option listen: bad port in "a:http"`},
+		{[]string{"--upstream", "ftp://example.com"}, `option upstream: "ftp://example.com" must have the scheme http or https`},
+		{[]string{"--via", "127.0.0.1:80"}, `option via: "127.0.0.1:80" is not an ipv6 address`},
+	}
+	for _, test := range tests {
+		err := ParseArgs(&Test{}, test.args)
+		if err == nil {
+			t.Fatalf("%q: expected error", test.args)
+		}
+		if !strings.Contains(err.Error(), test.err) {
+			t.Fatalf("%q: bad error: %s", test.args, err)
+		}
+	}
+
+	type BadTag struct {
+		Name string `args:"help='a name' scheme=https"`
+	}
+	if err := ParseArgs(&BadTag{}, nil); err == nil {
+		t.Fatal("expected error")
+	}
+
+	var buf bytes.Buffer
+	if err := Usage(&buf, Test{Listen: HostPort{"localhost", 8080}}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"--listen\t(default: localhost:8080)", "--peer\t(default: none)"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Fatalf("usage is missing %q:\n%s", want, buf.String())
+		}
+	}
+}
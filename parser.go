@@ -0,0 +1,225 @@
+package args
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ErrHelp is returned when Parser.Help is set and -h or --help is given,
+// but not declared.
+var ErrHelp = errors.New("args: help requested")
+
+// Parser parses command-line arguments with non-default settings.
+// The zero value parses the same way as Parse.
+type Parser struct {
+	// Name is the name of the program, used in usage and error messages.
+	// It defaults to the base name of os.Args[0].
+	Name string
+
+	// Stdout is where usage is written. It defaults to os.Stdout.
+	Stdout io.Writer
+
+	// Help makes -h and --help, unless the struct declares them, write
+	// the usage to Stdout, and the parse return ErrHelp. Otherwise they
+	// are unknown flags.
+	Help bool
+
+	// Stderr is where MustParse writes errors. It defaults to os.Stderr.
+	Stderr io.Writer
+
+	// StrictNumbers makes arguments that look like negative numbers, such
+	// as -5 or -1.5, values rather than flags, whatever field they are
+	// bound to. Declaring a digit as a short flag is an error.
+	//
+	// Otherwise, a negative number is a value if it is bound to a numeric
+	// field and its first digit isn't declared as a short flag: with -1
+	// declared, -5 is a value but -1.5 is a flag.
+	StrictNumbers bool
+
+	// AllowUnknown makes flags that don't match any field silently
+	// ignored, rather than an error.
+	AllowUnknown bool
+
+	// LookupEnv looks up environment variables. It defaults to
+	// os.LookupEnv.
+	LookupEnv func(key string) (string, bool)
+
+	// EnvPrefix, if set, gives every flag an environment variable, named
+	// with the prefix followed by the flag name in upper snake case. With
+	// the prefix MYAPP_, the flag --max-conns is set by MYAPP_MAX_CONNS.
+	// A flag with an env tag key uses the variable that the key names.
+	EnvPrefix string
+
+	// FlagName maps the name of a struct field to the name of its long
+	// flag, unless the field's tag names the flag. It defaults to
+	// KebabCase. Set it to Lowercase for the names that older versions
+	// of this package used.
+	FlagName func(field string) string
+
+	// ConfigFiles are read in order, before the file named by a flag with
+	// the config tag key, if there is one. Files that don't exist are
+	// skipped, unless they are named by a flag that was given.
+	//
+	// A file whose name ends in .json is JSON, and any other file is INI.
+	// Keys are the long names of flags, and sections or nested objects
+	// prefix their keys, the same way that groups prefix their flags. So
+	// these set --verbose and --db-host:
+	//
+	//	{"verbose": true, "db": {"host": "localhost"}}
+	//
+	//	verbose = true
+	//	[db]
+	//	host = localhost
+	//
+	// A section or object named after a command holds the settings of the
+	// command's flags, which are ignored unless the command is run. Lists
+	// are JSON arrays, comma separated values, or keys given more than once.
+	//
+	// The command line overrides the environment, which overrides config
+	// files, and later files override earlier ones.
+	ConfigFiles []string
+
+	// ConfigFlag is the name of a built-in flag that names a config file,
+	// such as "config". It works like a string field tagged with the
+	// config key, and is left out if the struct has a flag of that name.
+	// There is no such flag if it is empty.
+	ConfigFlag string
+
+	// FS is the file system that config files are read from. It defaults
+	// to the operating system's.
+	FS fs.FS
+
+	// IgnoreCase makes arguments match the choices of their fields
+	// regardless of case, as the ignorecase tag key does for one field.
+	IgnoreCase bool
+
+	// Now returns the current time, which times such as -2h or yesterday
+	// are relative to. It defaults to time.Now.
+	Now func() time.Time
+}
+
+// Parse parses os.Args[1:] into strukt, like the package-level Parse.
+func (p *Parser) Parse(strukt interface{}) error {
+	return p.ParseArgs(strukt, os.Args[1:])
+}
+
+// ParseArgs parses args into strukt, like the package-level Parse.
+func (p *Parser) ParseArgs(strukt interface{}, args []string) error {
+	return p.parse(strukt, args)
+}
+
+// Usage writes the usage for strukt to p.Stdout, like the package-level
+// Usage.
+func (p *Parser) Usage(strukt interface{}) error {
+	return p.usage(p.stdout(), strukt)
+}
+
+// MustParse parses os.Args[1:] into strukt. If help is requested, which
+// takes p.Help, it exits with status 0. On any other error, it writes the
+// error and the usage to p.Stderr and exits with status 2. The usage is
+// left out if strukt isn't a pointer to a struct.
+func (p *Parser) MustParse(strukt interface{}) {
+	var defaults interface{}
+	if typ, err := getType(strukt); err == nil && typ.Kind() == reflect.Struct {
+		defaults = reflect.ValueOf(strukt).Elem().Interface()
+	}
+	err := p.Parse(strukt)
+	if err == nil {
+		return
+	}
+	if err == ErrHelp {
+		exit(0)
+		return
+	}
+	fmt.Fprintln(p.stderr(), err)
+	if defaults != nil {
+		p.usage(p.stderr(), defaults)
+	}
+	exit(2)
+}
+
+// MustParse is like Parse, but exits the program if there is an error,
+// and handles -h and --help. See Parser.MustParse and Parser.Help.
+func MustParse(strukt interface{}) {
+	(&Parser{Help: true}).MustParse(strukt)
+}
+
+// exit is replaced in tests.
+var exit = os.Exit
+
+func (p *Parser) name() string {
+	if p.Name != "" {
+		return p.Name
+	}
+	return filepath.Base(os.Args[0])
+}
+
+func (p *Parser) stdout() io.Writer {
+	if p.Stdout != nil {
+		return p.Stdout
+	}
+	return os.Stdout
+}
+
+func (p *Parser) stderr() io.Writer {
+	if p.Stderr != nil {
+		return p.Stderr
+	}
+	return os.Stderr
+}
+
+func (p *Parser) now() time.Time {
+	if p.Now != nil {
+		return p.Now()
+	}
+	return time.Now()
+}
+
+func (p *Parser) flagName(field string) string {
+	if p.FlagName != nil {
+		return p.FlagName(field)
+	}
+	return KebabCase(field)
+}
+
+// KebabCase maps a field name such as MaxConns or HTTPPort to a flag name
+// such as max-conns or http-port. A lowercase letter and a digit after an
+// acronym are a version of it, so IPv6Addr maps to ipv6-addr, and a lone s
+// makes it plural, so URLs maps to urls.
+func KebabCase(field string) string {
+	runes := []rune(field)
+	var b strings.Builder
+	for i, r := range runes {
+		if r == '_' {
+			r = '-'
+		}
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			next := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			version := next && i+2 < len(runes) && unicode.IsDigit(runes[i+2])
+			plural := next && runes[i+1] == 's' && (i+2 == len(runes) || !unicode.IsLower(runes[i+2]))
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && next && !version && !plural) {
+				b.WriteRune('-')
+			}
+		}
+		if r == '-' && strings.HasSuffix(b.String(), "-") {
+			continue
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// Lowercase maps a field name such as MaxConns to a flag name such as
+// maxconns.
+func Lowercase(field string) string {
+	return strings.ToLower(field)
+}
@@ -0,0 +1,249 @@
+package args
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseArgs(t *testing.T) {
+	type Test struct {
+		Foo int    `args:"a foo,-f"`
+		Bar string `args:"a bar,r"`
+	}
+
+	var got Test
+	if err := ParseArgs(&got, []string{"-f", "5", "--bar", "x"}); err != nil {
+		t.Fatal(err)
+	}
+	if want := (Test{Foo: 5, Bar: "x"}); !reflect.DeepEqual(got, want) {
+		t.Fatalf("bad data: got %+v, want %+v", got, want)
+	}
+
+	p := &Parser{Name: "myprog"}
+	err := p.ParseArgs(&got, []string{"-f", "5"})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if want := "myprog: required argument was not supplied: --bar"; err.Error() != want {
+		t.Fatalf("bad error: got %q, want %q", err, want)
+	}
+}
+
+func TestParserFlagName(t *testing.T) {
+	type Test struct {
+		MaxConns int
+	}
+
+	p := &Parser{FlagName: strings.ToUpper}
+	var got Test
+	if err := p.ParseArgs(&got, []string{"--MAXCONNS", "5"}); err != nil {
+		t.Fatal(err)
+	}
+	if got.MaxConns != 5 {
+		t.Fatalf("bad data: %+v", got)
+	}
+}
+
+func TestHelp(t *testing.T) {
+	type Test struct {
+		Foo int `args:"a foo,-f"`
+	}
+
+	// Without Help, help flags are unknown, and nothing is written.
+	var buf bytes.Buffer
+	p := &Parser{Name: "myprog", Stdout: &buf}
+	got := Test{Foo: 3}
+	for _, args := range [][]string{{"--help"}, {"-h"}} {
+		if err := p.ParseArgs(&got, args); err == nil || err == ErrHelp {
+			t.Fatalf("%q: got error %v, want an unknown flag", args, err)
+		}
+		if buf.Len() != 0 {
+			t.Fatalf("%q: unexpected output %q", args, buf.String())
+		}
+	}
+
+	p.Help = true
+	for _, args := range [][]string{{"--help"}, {"-h"}, {"-f", "1", "-h"}} {
+		buf.Reset()
+		if err := p.ParseArgs(&got, args); err != ErrHelp {
+			t.Fatalf("%q: got error %v, want ErrHelp", args, err)
+		}
+		want := "usage: myprog [options]\n -f,\t--foo\t(default: 3)\ta foo\n"
+		if buf.String() != want {
+			t.Fatalf("%q: bad usage: got %q, want %q", args, buf.String(), want)
+		}
+	}
+
+	type Declared struct {
+		Help bool `args:"help me,-h"`
+	}
+	var d Declared
+	if err := p.ParseArgs(&d, []string{"-h"}); err != nil {
+		t.Fatal(err)
+	}
+	if !d.Help {
+		t.Fatal("expected -h to be set")
+	}
+}
+
+func TestMustParse(t *testing.T) {
+	origExit := exit
+	defer func() { exit = origExit }()
+	var code int
+	exit = func(c int) { code = c }
+
+	type Test struct {
+		Foo int `args:"a foo,-f"`
+	}
+
+	var stdout, stderr bytes.Buffer
+	p := &Parser{Name: "myprog", Stdout: &stdout, Stderr: &stderr, Help: true}
+
+	var got Test
+	restore := setArgs([]string{"myprog", "-f", "2"})
+	defer restore()
+	code = -1
+	p.MustParse(&got)
+	if code != -1 {
+		t.Fatalf("unexpected exit with status %d", code)
+	}
+	if got.Foo != 2 {
+		t.Fatalf("bad data: %+v", got)
+	}
+
+	setArgs([]string{"myprog", "--fo"})
+	p.MustParse(&got)
+	if code != 2 {
+		t.Fatalf("bad status: got %d, want 2", code)
+	}
+	if !strings.HasPrefix(stderr.String(), "args: unknown flag --fo, did you mean --foo?\nusage: myprog") {
+		t.Fatalf("bad output: %q", stderr.String())
+	}
+
+	setArgs([]string{"myprog", "--help"})
+	p.MustParse(&got)
+	if code != 0 {
+		t.Fatalf("bad status: got %d, want 0", code)
+	}
+	if !strings.HasPrefix(stdout.String(), "usage: myprog") {
+		t.Fatalf("bad output: %q", stdout.String())
+	}
+
+	// Bad values for strukt are reported, rather than panicking.
+	for _, strukt := range []interface{}{got, (*Test)(nil), nil} {
+		stderr.Reset()
+		code = -1
+		p.MustParse(strukt)
+		if code != 2 || !strings.HasPrefix(stderr.String(), "args: ") {
+			t.Fatalf("%T: bad exit: status %d, output %q", strukt, code, stderr.String())
+		}
+	}
+}
+
+// setArgs replaces os.Args, and returns a function that restores them.
+func setArgs(args []string) func() {
+	orig := os.Args
+	os.Args = args
+	return func() { os.Args = orig }
+}
+
+func TestKebabCase(t *testing.T) {
+	tests := map[string]string{
+		"Foo":        "foo",
+		"MaxConns":   "max-conns",
+		"HTTPPort":   "http-port",
+		"UserID":     "user-id",
+		"Int8ptr":    "int8ptr",
+		"Int8Ptr":    "int8-ptr",
+		"Max_Conns":  "max-conns",
+		"ServeHTTP2": "serve-http2",
+		"IPv6":       "ipv6",
+		"IPv6Addr":   "ipv6-addr",
+		"BindIPv4":   "bind-ipv4",
+		"JSONv2Mode": "jsonv2-mode",
+		"URLs":       "urls",
+		"IPs":        "ips",
+		"IDs":        "ids",
+		"UserIDs":    "user-ids",
+		"IDsByName":  "ids-by-name",
+		"HTTPServer": "http-server",
+	}
+	for field, want := range tests {
+		if got := KebabCase(field); got != want {
+			t.Errorf("KebabCase(%q): got %q, want %q", field, got, want)
+		}
+	}
+}
+
+func TestLongFlags(t *testing.T) {
+	type Test struct {
+		MaxConns  int    `args:"connection limit,-m"`
+		LogLevel  string `args:"log level,--verbosity,--log"`
+		UserAgent string
+	}
+
+	var got Test
+	testArgs := []string{"--max-conns", "5", "--log", "debug", "--user-agent", "curl"}
+	if err := ParseArgs(&got, testArgs); err != nil {
+		t.Fatal(err)
+	}
+	want := Test{MaxConns: 5, LogLevel: "debug", UserAgent: "curl"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("bad data: got %+v, want %+v", got, want)
+	}
+
+	got = Test{}
+	if err := ParseArgs(&got, []string{"--verbosity", "a", "--log", "b"}); err == nil {
+		t.Fatal("expected error")
+	}
+	got = Test{}
+	if err := ParseArgs(&got, []string{"--log-level", "a"}); err == nil {
+		t.Fatal("expected error")
+	}
+	got = Test{}
+	if err := ParseArgs(&got, []string{"--maxconns", "5"}); err == nil {
+		t.Fatal("expected error")
+	}
+
+	p := &Parser{FlagName: Lowercase}
+	got = Test{}
+	if err := p.ParseArgs(&got, []string{"--maxconns", "5", "--verbosity", "x"}); err != nil {
+		t.Fatal(err)
+	}
+	if want := (Test{MaxConns: 5, LogLevel: "x"}); !reflect.DeepEqual(got, want) {
+		t.Fatalf("bad data: got %+v, want %+v", got, want)
+	}
+
+	var buf bytes.Buffer
+	if err := Usage(&buf, Test{MaxConns: 10}); err != nil {
+		t.Fatal(err)
+	}
+	wantUsage := []string{
+		"usage: " + new(Parser).name() + " [options]",
+		" -m,\t--max-conns\t(default: 10)\tconnection limit",
+		" \t--verbosity, --log\t(default: \"\")\tlog level",
+		" \t--user-agent\t(default: \"\")\t",
+		"",
+	}
+	if got := strings.Split(buf.String(), "\n"); !reflect.DeepEqual(got, wantUsage) {
+		t.Fatalf("bad usage: got %q, want %q", got, wantUsage)
+	}
+
+	type Duplicate struct {
+		A string `args:",--x"`
+		X string
+	}
+	if err := ParseArgs(&Duplicate{}, nil); err == nil {
+		t.Fatal("expected error")
+	}
+	type DuplicateShort struct {
+		A string `args:",-x"`
+		B string `args:",-x"`
+	}
+	if err := ParseArgs(&DuplicateShort{}, nil); err == nil {
+		t.Fatal("expected error")
+	}
+}
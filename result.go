@@ -0,0 +1,131 @@
+package args
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+)
+
+// Source is where the value of a field came from.
+type Source int
+
+const (
+	SourceDefault Source = iota // the field was left as it was
+	SourceArgs                  // the command-line arguments
+	SourceEnv                   // an environment variable
+	SourceConfig                // a config file
+)
+
+func (s Source) String() string {
+	switch s {
+	case SourceDefault:
+		return "default"
+	case SourceArgs:
+		return "args"
+	case SourceEnv:
+		return "env"
+	case SourceConfig:
+		return "config"
+	}
+	return fmt.Sprintf("Source(%d)", int(s))
+}
+
+// Setting records the value of a field, and where it came from.
+type Setting struct {
+	Field   string      // the path of the field, such as DB.Host
+	Command string      // the names of the commands the field belongs to, if any
+	Flag    string      // the flag, such as --db-host, or the placeholder of a positional
+	Value   interface{} // the value of the field, or nil if it is a nil pointer
+	Source  Source
+
+	Arg  int    // the index in args of the argument that set the field
+	Env  string // the variable that set the field
+	File string // the file and line that set the field
+	Line int
+}
+
+// Origin describes where the value came from: "default", an argument such
+// as "args[3]", a variable such as "$PORT", or a file and line such as
+// "app.ini:3".
+func (s Setting) Origin() string {
+	switch s.Source {
+	case SourceArgs:
+		return fmt.Sprintf("args[%d]", s.Arg)
+	case SourceEnv:
+		return "$" + s.Env
+	case SourceConfig:
+		return fmt.Sprintf("%s:%d", s.File, s.Line)
+	}
+	return s.Source.String()
+}
+
+// Result describes how the fields of a struct were set by ParseResult. It
+// tells fields that were explicitly set to their zero value apart from
+// fields that were left alone.
+type Result struct {
+	// Settings holds the flags, positional arguments and rest arguments of
+	// the struct, and of the commands that were selected, in order.
+	Settings []Setting
+}
+
+// Lookup returns the setting of the field with the given path, such as
+// DB.Host. Fields of commands are prefixed with the path of the command's
+// field, as in Clone.Depth.
+func (r *Result) Lookup(field string) (Setting, bool) {
+	for _, set := range r.Settings {
+		if set.Field == field {
+			return set, true
+		}
+	}
+	return Setting{}, false
+}
+
+// IsSet returns true if the field with the given path was set by the
+// arguments, the environment or a config file.
+func (r *Result) IsSet(field string) bool {
+	set, ok := r.Lookup(field)
+	return ok && set.Source != SourceDefault
+}
+
+// Dump writes the effective configuration to w, one setting per line,
+// along with where each value came from.
+func (r *Result) Dump(w io.Writer) error {
+	for _, set := range r.Settings {
+		name := set.Flag
+		if set.Command != "" {
+			name = set.Command + " " + name
+		}
+		value := "<nil>"
+		if set.Value != nil {
+			value = formatValue(reflect.ValueOf(set.Value))
+		}
+		if _, err := fmt.Fprintf(w, "%s\t%s\t%s\n", name, value, set.Origin()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParseResult parses args into strukt, like ParseArgs, and describes
+// where the value of each field came from. The indexes of arguments in the
+// result are indexes in args.
+func (p *Parser) ParseResult(strukt interface{}, args []string) (*Result, error) {
+	if typ, err := getType(strukt); err != nil {
+		return nil, err
+	} else if typ.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("args: ParseResult needs a pointer to a struct, not %T", strukt)
+	}
+	res := new(Result)
+	if _, err := p.parseStruct(reflect.ValueOf(strukt).Elem(), args, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// ParseResult parses os.Args[1:] into strukt, like Parse, and describes
+// where the value of each field came from. The indexes of arguments in the
+// result are indexes in os.Args[1:].
+func ParseResult(strukt interface{}) (*Result, error) {
+	return new(Parser).ParseResult(strukt, os.Args[1:])
+}
@@ -0,0 +1,94 @@
+package args
+
+import (
+	"bytes"
+	"testing"
+	"testing/fstest"
+)
+
+func TestParseResult(t *testing.T) {
+	type Test struct {
+		Verbose bool     `args:"be chatty,-v"`
+		Name    string   `args:"a name,env=NAME"`
+		Port    int      `args:"a port"`
+		Size    int      `args:"a size"`
+		Ratio   *float64 `args:"a ratio"`
+		DB      DBOptions
+		File    string   `args:"a file,pos"`
+		Rest    []string `args:"the rest,rest"`
+	}
+	p := &Parser{
+		FS:          fstest.MapFS{"app.ini": {Data: []byte("port = 80\n[db]\nhost = example.com\n")}},
+		ConfigFiles: []string{"app.ini"},
+		LookupEnv:   env(map[string]string{"NAME": "from env"}),
+	}
+
+	got := Test{Size: 5}
+	res, err := p.ParseResult(&got, []string{"f.txt", "-v", "--size", "0", "--", "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		field  string
+		source Source
+		origin string
+	}{
+		{"Verbose", SourceArgs, "args[1]"},
+		{"Name", SourceEnv, "$NAME"},
+		{"Port", SourceConfig, "app.ini:1"},
+		{"Size", SourceArgs, "args[2]"},
+		{"Ratio", SourceDefault, "default"},
+		{"DB.Host", SourceConfig, "app.ini:3"},
+		{"DB.Port", SourceDefault, "default"},
+		{"File", SourceArgs, "args[0]"},
+		{"Rest", SourceArgs, "args[5]"},
+	}
+	for _, test := range tests {
+		set, ok := res.Lookup(test.field)
+		if !ok {
+			t.Fatalf("%s: not found", test.field)
+		}
+		if set.Source != test.source || set.Origin() != test.origin {
+			t.Fatalf("%s: bad source: got %s from %s, want %s from %s",
+				test.field, set.Source, set.Origin(), test.source, test.origin)
+		}
+	}
+
+	// Size was explicitly set to zero.
+	if got.Size != 0 || !res.IsSet("Size") || res.IsSet("DB.Port") {
+		t.Fatalf("bad result: %+v", res.Settings)
+	}
+
+	var buf bytes.Buffer
+	if err := res.Dump(&buf); err != nil {
+		t.Fatal(err)
+	}
+	want := "--verbose\ttrue\targs[1]\n" +
+		"--name\t\"from env\"\t$NAME\n" +
+		"--port\t80\tapp.ini:1\n" +
+		"--size\t0\targs[2]\n" +
+		"--ratio\t<nil>\tdefault\n" +
+		"--db-host\t\"example.com\"\tapp.ini:3\n" +
+		"--db-port\t0\tdefault\n" +
+		"--db-tls-cert\t\"\"\tdefault\n" +
+		"--db-tls-key\t\"\"\tdefault\n" +
+		"FILE\t\"f.txt\"\targs[0]\n" +
+		"REST...\t[x]\targs[5]\n"
+	if buf.String() != want {
+		t.Fatalf("bad dump: got\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestParseResultCommands(t *testing.T) {
+	res, err := new(Parser).ParseResult(&gitArgs{}, []string{"-v", "clone", "--depth", "3", "url"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	set, ok := res.Lookup("Clone.Depth")
+	if !ok || set.Command != "clone" || set.Origin() != "args[2]" || set.Value != 3 {
+		t.Fatalf("bad setting: %+v", set)
+	}
+	if set, _ := res.Lookup("Clone.URL"); set.Origin() != "args[4]" {
+		t.Fatalf("bad setting: %+v", set)
+	}
+}
@@ -0,0 +1,664 @@
+package args
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// option is a single flag or positional argument, backed by a struct field.
+type option struct {
+	name  string   // the primary long flag
+	longs []string // all of the long flags, starting with name
+	path  string   // the field's name, qualified by its group
+	env   string   // the environment variable that sets the flag, if any
+	group *group   // the group that the option belongs to, or nil
+	field reflect.StructField
+	value reflect.Value
+	tag   tagData
+	rules rules
+
+	requires []*option // the flags that must be given with this one
+}
+
+// group is a struct field whose fields are flags with a common prefix.
+type group struct {
+	path    string // the field's name, qualified by its parent group
+	prefix  string // the prefix of the long flags in the group, such as "db-"
+	heading string // the heading of the group in Usage
+}
+
+// spec describes the arguments that an args struct accepts.
+type spec struct {
+	value       reflect.Value // the struct
+	parent      *spec         // the spec of the parent command, or nil
+	path        []string      // the names of the commands leading to the struct
+	fieldPath   string        // the path of the command's field and a dot, or ""
+	commands    []*command
+	constraints []*constraint
+	flags       []*option
+	groups      []*group
+	long        map[string]*option
+	negated     map[string]*option // the --no- forms of boolean flags
+	short       map[string]*option
+	positionals []*option
+	rest        *option
+	extra       *Positionals
+	parser      *Parser
+
+	// structs are the types of the structs that addStruct is adding, from
+	// the outermost in, so that it can tell when a struct contains itself.
+	structs []reflect.Type
+}
+
+// newSpec builds the spec for the struct v, to be parsed by p. v must be
+// addressable.
+func newSpec(v reflect.Value, p *Parser) (*spec, error) {
+	s := &spec{
+		long:    make(map[string]*option),
+		negated: make(map[string]*option),
+		short:   make(map[string]*option),
+		parser:  p,
+		value:   v,
+	}
+	if err := s.addStruct(v, nil); err != nil {
+		return nil, err
+	}
+	if err := s.resolveRequires(); err != nil {
+		return nil, err
+	}
+	if len(s.commands) > 0 && (len(s.positionals) > 0 || s.extra != nil) {
+		return nil, fmt.Errorf(
+			"args: %s has commands, so it can't have positional arguments", v.Type())
+	}
+	return s, nil
+}
+
+// addStruct adds the fields of the struct v to the spec. Embedded structs
+// are flattened into g, and other struct fields become groups of their own.
+// A pointer to a struct is only a group if it is embedded or tagged with
+// help or prefix, so that a struct can point to others of its type.
+func (s *spec) addStruct(v reflect.Value, g *group) error {
+	typ := v.Type()
+	for _, outer := range s.structs {
+		if outer == typ {
+			return fmt.Errorf("args: %s contains itself", typ)
+		}
+	}
+	s.structs = append(s.structs, typ)
+	defer func() { s.structs = s.structs[:len(s.structs)-1] }()
+	for i := 0; i < v.NumField(); i++ {
+		field := typ.Field(i)
+		if field.Anonymous && field.Type == reflect.TypeOf(Positionals{}) {
+			s.extra = v.Field(i).Addr().Interface().(*Positionals)
+			continue
+		}
+		if field.PkgPath != "" {
+			// Non-empty PkgPath implies unexported field
+			continue
+		}
+		path := field.Name
+		if g != nil {
+			path = g.path + "." + field.Name
+		}
+		td, err := parseTagData(field.Tag)
+		if err != nil {
+			return fmt.Errorf("args: bad tag on field %s: %s", path, err)
+		}
+		fval := v.Field(i)
+		if td.Command != nil {
+			if err := s.addCommand(field, path, td, g, fval); err != nil {
+				return err
+			}
+			continue
+		}
+		if isGroup(field.Type) && field.Type.Kind() == reflect.Ptr && !field.Anonymous &&
+			td.Prefix == nil && td.Description == "" {
+			continue
+		}
+		if isGroup(field.Type) {
+			if fval.Kind() == reflect.Ptr {
+				if fval.IsNil() {
+					fval.Set(reflect.New(field.Type.Elem()))
+				}
+				fval = fval.Elem()
+			}
+			if field.Anonymous {
+				if err := s.addStruct(fval, g); err != nil {
+					return err
+				}
+				continue
+			}
+			sub, err := s.newGroup(field, path, td, g)
+			if err != nil {
+				return err
+			}
+			if err := s.addStruct(fval, sub); err != nil {
+				return err
+			}
+			continue
+		}
+		if field.Anonymous {
+			continue
+		}
+		if td.Prefix != nil {
+			return fmt.Errorf("args: bad tag on field %s: only groups can have a prefix", path)
+		}
+		opt := &option{
+			name:  s.parser.flagName(field.Name),
+			path:  path,
+			group: g,
+			field: field,
+			value: fval,
+			tag:   td,
+		}
+		if err := s.addOption(opt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newGroup adds a group for the struct field, which belongs to parent.
+func (s *spec) newGroup(field reflect.StructField, path string, td tagData, parent *group) (*group, error) {
+	if td.ShortFlag != "" || len(td.LongFlags) > 0 || td.Required || td.Positional || td.Rest || td.Command != nil {
+		return nil, fmt.Errorf("args: bad tag on field %s: groups only take help and prefix", path)
+	}
+	g := &group{path: path, heading: td.Description}
+	if td.Prefix != nil {
+		g.prefix = *td.Prefix
+	} else {
+		g.prefix = s.parser.flagName(field.Name)
+	}
+	if g.prefix != "" && !strings.HasSuffix(g.prefix, "-") {
+		g.prefix += "-"
+	}
+	if parent != nil {
+		g.prefix = parent.prefix + g.prefix
+	}
+	if g.heading == "" {
+		g.heading = path + " options"
+	}
+	s.groups = append(s.groups, g)
+	return g, nil
+}
+
+// addOption adds a flag or positional argument to the spec.
+func (s *spec) addOption(opt *option) error {
+	if opt.tag.Env != nil && (opt.tag.Rest || opt.tag.Positional) {
+		return fmt.Errorf("args: bad tag on field %s: only flags can be set by env", opt.path)
+	}
+	if opt.tag.Config && (opt.tag.Rest || opt.tag.Positional || indirect(opt.field.Type).Kind() != reflect.String) {
+		return fmt.Errorf("args: bad tag on field %s: only string flags can name config files", opt.path)
+	}
+	if len(opt.tag.Layouts) > 0 && !isTime(opt.field.Type) {
+		return fmt.Errorf("args: bad tag on field %s: only times have layouts", opt.path)
+	}
+	if opt.tag.Port != "" && !hasPorts(opt.field.Type) {
+		return fmt.Errorf("args: bad tag on field %s: only hosts and ports have a default port", opt.path)
+	}
+	if opt.tag.Family != "" && !hasAddrs(opt.field.Type) {
+		return fmt.Errorf("args: bad tag on field %s: only IP addresses have a family", opt.path)
+	}
+	if len(opt.tag.Schemes) > 0 && !hasURLs(opt.field.Type) {
+		return fmt.Errorf("args: bad tag on field %s: only URLs have schemes", opt.path)
+	}
+	if len(opt.tag.Choices) > 0 && kindOf(baseType(opt.field.Type)) == reflect.Bool {
+		return fmt.Errorf("args: bad tag on field %s: booleans can't have choices", opt.path)
+	}
+	if opt.tag.SI && !isInteger(opt.field.Type) {
+		return fmt.Errorf("args: bad tag on field %s: only integers have SI suffixes", opt.path)
+	}
+	if opt.tag.Count && (opt.tag.Rest || opt.tag.Positional || !isInteger(opt.field.Type) || kindOf(opt.field.Type) == reflect.Slice) {
+		return fmt.Errorf("args: bad tag on field %s: only integer flags can count", opt.path)
+	}
+	if err := opt.parseRules(); err != nil {
+		return err
+	}
+	if opt.tag.Default != nil {
+		// Make sure that the default parses, without setting the field.
+		scratch := *opt
+		scratch.value = reflect.New(opt.field.Type).Elem()
+		if err := s.parser.setDefault(&scratch); err != nil {
+			return fmt.Errorf("args: bad tag on field %s: bad default: %s", opt.path,
+				strings.TrimPrefix(err.Error(), "args: "))
+		}
+	}
+	if opt.tag.Implicit != nil {
+		if opt.tag.Rest || opt.tag.Positional || opt.tag.Count || kindOf(opt.field.Type) == reflect.Bool ||
+			kindOf(opt.field.Type) == reflect.Slice {
+			return fmt.Errorf("args: bad tag on field %s: only flags that take one value can have an implicit value", opt.path)
+		}
+		scratch := *opt
+		scratch.value = reflect.New(opt.field.Type).Elem()
+		if err := s.parser.setOption(&scratch, []string{*opt.tag.Implicit}); err != nil {
+			return fmt.Errorf("args: bad tag on field %s: bad implicit value: %s", opt.path,
+				strings.TrimPrefix(err.Error(), "args: "))
+		}
+	}
+	td := opt.tag
+	if (td.Rest || td.Positional) && (td.Xor != "" || td.OneOf != "" || td.AnyOf != "" || len(td.Requires) > 0) {
+		return fmt.Errorf("args: bad tag on field %s: only flags can have constraints", opt.path)
+	}
+	if opt.tag.Rest {
+		return s.setRest(opt)
+	}
+	if opt.tag.Positional {
+		return s.addPositional(opt)
+	}
+	opt.longs = opt.tag.LongFlags
+	if len(opt.longs) == 0 {
+		opt.longs = []string{opt.name}
+	}
+	if opt.group != nil {
+		for i, long := range opt.longs {
+			opt.longs[i] = opt.group.prefix + long
+		}
+	}
+	opt.name = opt.longs[0]
+	if err := s.addConstraints(opt); err != nil {
+		return err
+	}
+	if opt.tag.Env != nil && *opt.tag.Env != "" {
+		opt.env = *opt.tag.Env
+	} else if opt.tag.Env != nil || s.parser.EnvPrefix != "" {
+		opt.env = s.parser.EnvPrefix + upperSnake(opt.name)
+	}
+	s.flags = append(s.flags, opt)
+	for _, long := range opt.longs {
+		other, ok := s.long[long]
+		if !ok {
+			other, ok = s.negated[long]
+		}
+		if ok {
+			return fmt.Errorf(
+				"args: flag --%s is declared by both %s and %s",
+				long, other.path, opt.path)
+		}
+		s.long[long] = opt
+	}
+	if opt.negatable() {
+		for _, long := range opt.longs {
+			if other, ok := s.long["no-"+long]; ok {
+				return fmt.Errorf(
+					"args: flag --no-%s is declared by both %s and %s",
+					long, other.path, opt.path)
+			}
+			s.negated["no-"+long] = opt
+		}
+	}
+	if short := opt.tag.ShortFlag; short != "" {
+		if other, ok := s.short[short]; ok {
+			return fmt.Errorf(
+				"args: flag -%s is declared by both %s and %s",
+				short, other.path, opt.path)
+		}
+		if isDigit(short[0]) {
+			if s.parser.StrictNumbers {
+				return fmt.Errorf(
+					"args: short flag -%s of %s is ambiguous with negative numbers",
+					short, opt.path)
+			}
+		}
+		s.short[short] = opt
+	}
+	return nil
+}
+
+// isGroup is true if fields of type t hold groups of options, rather than
+// a single option.
+func isGroup(t reflect.Type) bool {
+	return indirect(t).Kind() == reflect.Struct && !isValue(t)
+}
+
+// addPositional appends opt to the positional arguments, making sure that
+// the positional arguments can be bound unambiguously.
+func (s *spec) addPositional(opt *option) error {
+	if n := len(s.positionals); n > 0 {
+		last := s.positionals[n-1]
+		if last.variadic() {
+			return fmt.Errorf(
+				"args: positional argument %s follows variadic argument %s",
+				opt.placeholder(), last.placeholder())
+		}
+		if !last.required() && opt.required() {
+			return fmt.Errorf(
+				"args: required positional argument %s follows optional argument %s",
+				opt.placeholder(), last.placeholder())
+		}
+	}
+	s.positionals = append(s.positionals, opt)
+	return nil
+}
+
+// setRest makes opt the field that receives the arguments after "--".
+func (s *spec) setRest(opt *option) error {
+	if s.rest != nil {
+		return fmt.Errorf(
+			"args: only one field can be tagged rest, found %s and %s",
+			s.rest.path, opt.path)
+	}
+	if t := indirect(opt.field.Type); t.Kind() != reflect.Slice || t.Elem().Kind() != reflect.String {
+		return fmt.Errorf(
+			"args: rest field %s must be a []string, not %s",
+			opt.path, opt.field.Type)
+	}
+	s.rest = opt
+	return nil
+}
+
+// lookup returns the flag that the token flag names, such as -n or
+// --name, or nil if there is no such flag. Short and long flags are looked
+// up separately, so that -n can't set a field whose long flag is --n.
+// Commands inherit the flags of their parents.
+func (s *spec) lookup(flag string) *option {
+	if strings.HasPrefix(flag, "--") {
+		return s.lookupLong(flag[2:])
+	}
+	return s.lookupShort(strings.TrimPrefix(flag, "-"))
+}
+
+// lookupLong returns the long flag called key, or nil if there is no such
+// flag.
+func (s *spec) lookupLong(key string) *option {
+	for ; s != nil; s = s.parent {
+		if opt, ok := s.long[key]; ok {
+			return opt
+		}
+	}
+	return nil
+}
+
+// lookupShort returns the short flag called key, or nil if there is no
+// such flag.
+func (s *spec) lookupShort(key string) *option {
+	for ; s != nil; s = s.parent {
+		if opt, ok := s.short[key]; ok {
+			return opt
+		}
+	}
+	return nil
+}
+
+// canonical returns the key that the flag token flag is lexed under: the
+// name of the flag, or the token itself if there is no such flag, so that
+// an unknown flag can't be taken for a known one. Without a spec, flags
+// are keyed as they were given, without their dashes.
+func (s *spec) canonical(flag string) string {
+	if s == nil {
+		return strings.TrimPrefix(strings.TrimPrefix(flag, "-"), "-")
+	}
+	if opt := s.lookup(flag); opt != nil {
+		return opt.name
+	}
+	return flag
+}
+
+// takesValue is true if flag is a known flag that takes at least one value.
+func (s *spec) takesValue(flag string) bool {
+	return s.lookup(flag) != nil && s.arity(flag) != 0
+}
+
+// bareValues returns the values of the flag token flag when it is given
+// without any. Each switch of a counter is an empty value, so that they add
+// up, and a boolean is true, so that the last of --x and --no-x wins. A
+// flag with an implicit value takes that.
+func (s *spec) bareValues(flag string) []string {
+	opt := s.lookup(flag)
+	switch {
+	case opt == nil:
+		return nil
+	case opt.tag.Count:
+		return []string{""}
+	case opt.tag.Implicit != nil:
+		return []string{*opt.tag.Implicit}
+	case kindOf(opt.field.Type) == reflect.Bool:
+		return []string{"true"}
+	}
+	return nil
+}
+
+// allShort is true if every character of cluster is a known short flag.
+func (s *spec) allShort(cluster string) bool {
+	if s == nil {
+		return false
+	}
+	for i := 0; i < len(cluster); i++ {
+		if s.lookupShort(cluster[i:i+1]) == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// munchArgs munches the values of the flag token flag from the front of
+// args, stopping at the next flag or when the flag has all of its values.
+// It returns the munched items.
+func (s *spec) munchArgs(args []string, flag string) []string {
+	n := s.arity(flag)
+	opt := s.lookup(flag)
+	result := []string{}
+	for len(args) > 0 && len(result) != n {
+		arg := args[0]
+		if strings.HasPrefix(arg, "-") && arg != "-" && !s.negativeValue(arg, opt) {
+			break
+		}
+		result = append(result, arg)
+		args = args[1:]
+	}
+	return result
+}
+
+// negativeValue is true if arg is a negative number that should be read
+// as a value of opt, rather than as a flag. opt may be nil if the flag is
+// unknown. Without a spec, every negative number is a value.
+func (s *spec) negativeValue(arg string, opt *option) bool {
+	if opt != nil && opt.temporal() && !s.digitFlag(arg) && isNegativeDuration(arg) {
+		return true
+	}
+	if !isNegativeNumber(arg) {
+		return false
+	}
+	if s == nil || s.parser.StrictNumbers {
+		return true
+	}
+	return opt != nil && opt.numeric() && !s.digitFlag(arg)
+}
+
+// digitFlag is true if arg starts with a short flag that is a digit, such
+// as -1 in -1.5, which makes it a flag rather than a negative number.
+func (s *spec) digitFlag(arg string) bool {
+	return s != nil && len(arg) > 1 && isDigit(arg[1]) && s.lookupShort(arg[1:2]) != nil
+}
+
+// positionalAt returns the positional field that the i'th positional
+// argument is bound to, or nil if there isn't one.
+func (s *spec) positionalAt(i int) *option {
+	for j, opt := range s.positionals {
+		if i == j || (i > j && opt.variadic()) {
+			return opt
+		}
+	}
+	return nil
+}
+
+// arity returns the number of values that the flag token flag takes, or -1
+// if it takes any number of values. A nil spec knows nothing about its
+// flags, so all of them take any number of values.
+func (s *spec) arity(flag string) int {
+	opt := s.lookup(flag)
+	if opt == nil {
+		return -1
+	}
+	if opt.tag.Count || opt.tag.Implicit != nil {
+		// The value of --name=value is attached, so it isn't counted.
+		return 0
+	}
+	switch kindOf(opt.field.Type) {
+	case reflect.Bool:
+		return 0
+	case reflect.Slice:
+		return -1
+	default:
+		return 1
+	}
+}
+
+// setPositionals binds args to the positional fields, in order. Arguments
+// that are left over are stored in the embedded Positionals, if there is one.
+// index holds the index of each of args in the arguments given to Parse.
+// Like level.apply, it returns every error.
+func (s *spec) setPositionals(args []string, index []int, st *parseState) []error {
+	var errs []error
+	for _, opt := range s.positionals {
+		if len(args) == 0 {
+			if opt.tag.Default != nil {
+				if err := s.parser.setDefault(opt); err != nil {
+					errs = append(errs, err)
+					continue
+				}
+			} else if opt.required() {
+				errs = append(errs, fmt.Errorf(
+					"%s: required argument was not supplied: %s",
+					s.parser.name(), opt.placeholder()))
+				continue
+			}
+			st.record(s, opt, Setting{Source: SourceDefault})
+			continue
+		}
+		n := 1
+		if opt.variadic() {
+			n = len(args)
+		}
+		if err := s.parser.setOption(opt, args[:n]); err != nil {
+			errs = append(errs, err)
+			st.failed[opt] = true
+		} else {
+			st.record(s, opt, Setting{Source: SourceArgs, Arg: index[0]})
+		}
+		args, index = args[n:], index[n:]
+	}
+	if len(args) > 0 {
+		if s.extra == nil {
+			return append(errs, fmt.Errorf("args: unexpected argument %q", args[0]))
+		}
+		s.extra.data = args
+	}
+	return errs
+}
+
+// synopsis returns a one line summary of how to invoke the program.
+func (s *spec) synopsis() string {
+	parts := append([]string{s.parser.name()}, s.path...)
+	if len(s.flags) > 0 || s.parent != nil {
+		parts = append(parts, "[options]")
+	}
+	for _, c := range s.constraints {
+		parts = append(parts, c.synopsis())
+	}
+	if len(s.commands) > 0 {
+		if s.runner() != nil {
+			parts = append(parts, "[COMMAND]")
+		} else {
+			parts = append(parts, "COMMAND")
+		}
+	}
+	for _, opt := range s.positionals {
+		p := opt.placeholder()
+		if !opt.required() {
+			p = "[" + p + "]"
+		}
+		parts = append(parts, p)
+	}
+	if s.extra != nil {
+		parts = append(parts, "[ARGS...]")
+	}
+	if s.rest != nil {
+		parts = append(parts, "[-- "+s.rest.placeholder()+"]")
+	}
+	return strings.Join(parts, " ")
+}
+
+// variadic is true if the option consumes all remaining positional arguments.
+func (opt *option) variadic() bool {
+	return kindOf(opt.field.Type) == reflect.Slice
+}
+
+// numeric is true if the option holds numbers.
+func (opt *option) numeric() bool {
+	if isValue(opt.field.Type) {
+		return false
+	}
+	t := indirect(opt.field.Type)
+	if t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	switch kindOf(t) {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// required is true if the option must be supplied. Positional arguments
+// are required unless they are pointers or slices. Options with defaults
+// are never required.
+func (opt *option) required() bool {
+	if opt.tag.Default != nil {
+		return false
+	}
+	if opt.tag.Required {
+		return true
+	}
+	if !opt.tag.Positional {
+		return false
+	}
+	return opt.field.Type.Kind() != reflect.Ptr && kindOf(opt.field.Type) != reflect.Slice
+}
+
+// placeholder is the name of a positional argument, as shown by Usage.
+func (opt *option) placeholder() string {
+	p := strings.ToUpper(opt.field.Name)
+	if opt.variadic() {
+		p += "..."
+	}
+	return p
+}
+
+// baseType returns the type of the values that a field of type t holds,
+// looking through pointers and slices, unless t is a value itself.
+func baseType(t reflect.Type) reflect.Type {
+	t = indirect(t)
+	if t.Kind() == reflect.Slice && !isValue(t) {
+		t = indirect(t.Elem())
+	}
+	return t
+}
+
+// indirect returns the type that t points to, or t if it is not a pointer.
+func indirect(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Ptr {
+		return t.Elem()
+	}
+	return t
+}
+
+// isNegativeNumber is true if arg is a negative number, such as -5, -0x1f
+// or -1.5e3.
+func isNegativeNumber(arg string) bool {
+	if len(arg) < 2 || arg[0] != '-' || !(isDigit(arg[1]) || arg[1] == '.') {
+		return false
+	}
+	if _, err := strconv.ParseInt(arg, 0, 64); err == nil {
+		return true
+	}
+	_, err := strconv.ParseFloat(arg, 64)
+	return err == nil
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
@@ -0,0 +1,149 @@
+package args
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type TLSOptions struct {
+	Cert string `args:"certificate file"`
+	Key  string `args:"key file"`
+}
+
+type DBOptions struct {
+	Host string `args:"database host"`
+	Port int    `args:"database port"`
+	TLS  TLSOptions
+}
+
+type LogOptions struct {
+	Verbose bool `args:"be chatty,-v"`
+}
+
+type GroupTest struct {
+	LogOptions
+	Name  string      `args:"a name"`
+	DB    DBOptions   `args:"help='Database options'"`
+	Cache *DBOptions  `args:"prefix=redis"`
+	Peer  *TLSOptions `args:"prefix=''"`
+}
+
+func TestGroups(t *testing.T) {
+	var got GroupTest
+	testArgs := []string{
+		"-v",
+		"--name", "foo",
+		"--db-host", "localhost",
+		"--db-port", "5432",
+		"--db-tls-cert", "db.crt",
+		"--redis-host", "cache",
+		"--redis-tls-key", "cache.key",
+		"--cert", "peer.crt",
+	}
+	if err := ParseArgs(&got, testArgs); err != nil {
+		t.Fatal(err)
+	}
+	want := GroupTest{
+		LogOptions: LogOptions{Verbose: true},
+		Name:       "foo",
+		DB: DBOptions{
+			Host: "localhost",
+			Port: 5432,
+			TLS:  TLSOptions{Cert: "db.crt"},
+		},
+		Cache: &DBOptions{Host: "cache", TLS: TLSOptions{Key: "cache.key"}},
+		Peer:  &TLSOptions{Cert: "peer.crt"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("bad data: got %+v, want %+v", got, want)
+	}
+
+	if err := ParseArgs(&GroupTest{}, []string{"--host", "x"}); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestGroupUsage(t *testing.T) {
+	var buf bytes.Buffer
+	p := &Parser{Name: "prog"}
+	if err := p.usage(&buf, GroupTest{DB: DBOptions{Port: 5432}}); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"usage: prog [options]",
+		" -v,\t--[no-]verbose\t(default: false)\tbe chatty",
+		" \t--name\t(default: \"\")\ta name",
+		"",
+		"Database options:",
+		" \t--db-host\t(default: \"\")\tdatabase host",
+		" \t--db-port\t(default: 5432)\tdatabase port",
+		"",
+		"DB.TLS options:",
+		" \t--db-tls-cert\t(default: \"\")\tcertificate file",
+		" \t--db-tls-key\t(default: \"\")\tkey file",
+		"",
+		"Cache options:",
+		" \t--redis-host\t(default: \"\")\tdatabase host",
+		" \t--redis-port\t(default: 0)\tdatabase port",
+		"",
+		"Cache.TLS options:",
+		" \t--redis-tls-cert\t(default: \"\")\tcertificate file",
+		" \t--redis-tls-key\t(default: \"\")\tkey file",
+		"",
+		"Peer options:",
+		" \t--cert\t(default: \"\")\tcertificate file",
+		" \t--key\t(default: \"\")\tkey file",
+		"",
+	}
+	if got := strings.Split(buf.String(), "\n"); !reflect.DeepEqual(got, want) {
+		t.Fatalf("bad usage: got %q, want %q", got, want)
+	}
+}
+
+func TestGroupErrors(t *testing.T) {
+	type Conflict struct {
+		TLSOptions
+		Peer TLSOptions `args:"prefix=''"`
+	}
+	if err := ParseArgs(&Conflict{}, nil); err == nil {
+		t.Fatal("expected error")
+	}
+	type ShortGroup struct {
+		DB DBOptions `args:",-d"`
+	}
+	if err := ParseArgs(&ShortGroup{}, nil); err == nil {
+		t.Fatal("expected error")
+	}
+	type PrefixField struct {
+		Name string `args:"prefix=x"`
+	}
+	if err := ParseArgs(&PrefixField{}, nil); err == nil {
+		t.Fatal("expected error")
+	}
+	type Cycle struct {
+		X    int
+		Next *Cycle `args:"prefix=next"`
+	}
+	if err := ParseArgs(&Cycle{}, nil); err == nil || err.Error() != "args: args.Cycle contains itself" {
+		t.Fatalf("bad error: %v", err)
+	}
+}
+
+func TestUntaggedStructPointers(t *testing.T) {
+	type list struct {
+		X    int
+		Next *list
+	}
+	var got list
+	if err := ParseArgs(&got, []string{"--x", "1"}); err != nil {
+		t.Fatal(err)
+	}
+	if got.X != 1 || got.Next != nil {
+		t.Fatalf("bad data: %+v", got)
+	}
+	if err := ParseArgs(&got, []string{"--next-x", "1"}); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -0,0 +1,162 @@
+package args
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// checkLong returns an error if key is not a known long flag. arg is the
+// argument that key was found in.
+func (s *spec) checkLong(key, arg string) error {
+	if s == nil {
+		return nil
+	}
+	if s.lookupLong(key) != nil {
+		return nil
+	}
+	if key == "help" && s.parser.Help {
+		return ErrHelp
+	}
+	if s.parser.AllowUnknown {
+		return nil
+	}
+	return s.unknownFlag("--"+key, arg)
+}
+
+// checkShort returns an error if key is not a known short flag. arg is the
+// argument that key was found in, which may be a cluster of short flags.
+func (s *spec) checkShort(key, arg string) error {
+	if s == nil {
+		return nil
+	}
+	if s.lookupShort(key) != nil {
+		return nil
+	}
+	if key == "h" && s.parser.Help {
+		return ErrHelp
+	}
+	if s.parser.AllowUnknown {
+		return nil
+	}
+	return s.unknownFlag("-"+key, arg)
+}
+
+// unknownFlag returns an error for the unknown flag, suggesting the
+// declared flags that are closest to what was typed.
+func (s *spec) unknownFlag(flag, arg string) error {
+	msg := "args: unknown flag " + flag
+	if eq := strings.Index(arg, "="); eq >= 0 && strings.HasPrefix(arg, "--") {
+		arg = arg[:eq]
+	}
+	if arg != flag {
+		msg += " in " + arg
+	}
+	// A whole argument like -verbose is more telling than the flag -e in it.
+	suggestions := s.suggest(arg)
+	if len(suggestions) == 0 {
+		suggestions = s.suggest(flag)
+	}
+	if len(suggestions) > 0 {
+		msg += ", did you mean " + orList(suggestions) + "?"
+	}
+	return errors.New(msg)
+}
+
+// maxSuggestions is the most flags that an error will suggest.
+const maxSuggestions = 3
+
+// suggest returns the declared flags that are closest to typed.
+func (s *spec) suggest(typed string) []string {
+	var candidates []string
+	for ; s != nil; s = s.parent {
+		for _, opt := range s.flags {
+			for _, long := range opt.longs {
+				candidates = append(candidates, "--"+long)
+			}
+			if opt.tag.ShortFlag != "" {
+				candidates = append(candidates, "-"+opt.tag.ShortFlag)
+			}
+		}
+	}
+	return closest(typed, candidates)
+}
+
+// closest returns the candidates with the smallest edit distance to typed.
+// Candidates that are too far away to be a typo are left out.
+func closest(typed string, candidates []string) []string {
+	best := -1
+	var result []string
+	for _, c := range candidates {
+		d := editDistance(typed, c)
+		if d > maxTypos(c) {
+			continue
+		}
+		if best < 0 || d < best {
+			best = d
+			result = result[:0]
+		}
+		if d == best {
+			result = append(result, c)
+		}
+	}
+	sort.Strings(result)
+	if len(result) > maxSuggestions {
+		result = result[:maxSuggestions]
+	}
+	return result
+}
+
+// maxTypos is the largest edit distance from flag that is considered a typo.
+func maxTypos(flag string) int {
+	n := len(strings.TrimLeft(flag, "-")) / 3
+	if n < 1 {
+		return 1
+	}
+	return n
+}
+
+// editDistance returns the edit distance between a and b, counting
+// insertions, deletions, substitutions and transpositions of adjacent
+// characters.
+func editDistance(a, b string) int {
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = min3(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] && d[i-2][j-2]+1 < d[i][j] {
+				d[i][j] = d[i-2][j-2] + 1
+			}
+		}
+	}
+	return d[len(a)][len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
+
+// orList joins items as "a", "a or b", or "a, b or c".
+func orList(items []string) string {
+	if len(items) == 1 {
+		return items[0]
+	}
+	return strings.Join(items[:len(items)-1], ", ") + " or " + items[len(items)-1]
+}
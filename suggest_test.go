@@ -0,0 +1,83 @@
+package args
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUnknownFlags(t *testing.T) {
+	type Test struct {
+		Verbose bool   `args:"be chatty,-v"`
+		Version bool   `args:"print the version"`
+		Name    string `args:"a name,-n"`
+	}
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{
+			[]string{"--verbos"},
+			"args: unknown flag --verbos, did you mean --verbose?",
+		},
+		{
+			[]string{"--versio=1"},
+			"args: unknown flag --versio, did you mean --version?",
+		},
+		{
+			[]string{"--nmae", "foo"},
+			"args: unknown flag --nmae, did you mean --name?",
+		},
+		{
+			[]string{"-verbose"},
+			"args: unknown flag -e in -verbose, did you mean --verbose?",
+		},
+		{
+			[]string{"-x"},
+			"args: unknown flag -x, did you mean -n or -v?",
+		},
+		{
+			[]string{"--frobnicate"},
+			"args: unknown flag --frobnicate",
+		},
+	}
+
+	for _, test := range tests {
+		var got Test
+		err := parse(&got, test.args)
+		if err == nil {
+			t.Fatalf("%q: expected error", test.args)
+		}
+		if err.Error() != test.want {
+			t.Errorf("%q: got error %q, want %q", test.args, err, test.want)
+		}
+	}
+
+	p := &Parser{AllowUnknown: true}
+	var got Test
+	if err := p.parse(&got, []string{"--verbos", "x", "-v", "-q", "--name", "foo"}); err != nil {
+		t.Fatal(err)
+	}
+	if want := (Test{Verbose: true, Name: "foo"}); !reflect.DeepEqual(got, want) {
+		t.Fatalf("bad data: got %+v, want %+v", got, want)
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"", "abc", 3},
+		{"verbose", "verbos", 1},
+		{"name", "nmae", 1},
+		{"kitten", "sitting", 3},
+	}
+	for _, test := range tests {
+		if got := editDistance(test.a, test.b); got != test.want {
+			t.Errorf("editDistance(%q, %q): got %d, want %d", test.a, test.b, got, test.want)
+		}
+	}
+}
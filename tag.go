@@ -0,0 +1,454 @@
+package args
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+/*
+tagData holds the settings from the struct tags of a field.
+
+The args tag has two forms. The original form is a description followed by
+comma separated options:
+
+	`args:"size in bytes,-s,--size,r"`
+
+Commas in the description can be escaped with a backslash. The structured
+form is a space separated list of keys, some of which take values:
+
+	`args:"help='size, in bytes' short=s long=size required"`
+
+Values can be quoted with single or double quotes, and backslash escapes
+quotes and spaces. The options of the original form can also be written
+as keys, as in `args:"size in bytes,short=s"`.
+
+The help and short keys can be given as separate struct tags too:
+
+	`help:"size, in bytes" short:"s"`
+
+Other keys can't, so that tags meant for other packages, such as default
+or env, are left alone.
+*/
+type tagData struct {
+	Description string
+	Required    bool
+	ShortFlag   string
+	LongFlags   []string
+	Positional  bool
+	Rest        bool
+	Prefix      *string // nil if the prefix is not set
+	Command     *string // nil if the field is not a command
+	Env         *string // nil if the env key is not given
+	Config      bool    // the flag names a config file
+	Default     *string // nil if there is no default
+	Implicit    *string // the value of the flag when it's given without one, or nil
+	Layouts     []string
+	Port        string   // the default port of hosts
+	Family      string   // ipv4 or ipv6
+	Schemes     []string // the schemes that URLs may have
+	SI          bool     // integers can have SI suffixes
+	Choices     []string // the values that the option can take
+	IgnoreCase  bool     // choices are matched regardless of case
+	Count       bool     // the flag counts how many times it is given
+	CountCap    int      // the most that the flag counts to, or 0 if there's no cap
+	NoNegate    bool     // a boolean flag has no --no- form
+
+	// Constraints
+	Xor, OneOf, AnyOf string   // the names of the sets of flags that the flag is in
+	Requires          []string // the flags that must be given with the flag
+
+	// Validation rules
+	Min, Max     string
+	Len          string
+	Pattern      string
+	Nonempty     bool
+	ExistingFile bool
+	ExistingDir  bool
+}
+
+// tagKey is a key of the structured tag form.
+type tagKey struct {
+	// hasValue is true if the key takes a value.
+	hasValue bool
+
+	// optional is true if the value of the key can be left out.
+	optional bool
+
+	// repeat is true if the key can be given more than once.
+	repeat bool
+
+	set func(td *tagData, value string) error
+}
+
+var tagKeys = map[string]tagKey{
+	"help": {hasValue: true, set: func(td *tagData, v string) error {
+		td.Description = v
+		return nil
+	}},
+	"short": {hasValue: true, set: func(td *tagData, v string) error {
+		v = strings.TrimPrefix(v, "-")
+		if len(v) != 1 || v == "-" {
+			return fmt.Errorf("short flag %q must be a single character", v)
+		}
+		td.ShortFlag = v
+		return nil
+	}},
+	"long": {hasValue: true, repeat: true, set: func(td *tagData, v string) error {
+		v = strings.TrimPrefix(v, "--")
+		if v == "" || strings.ContainsAny(v, "= \t") {
+			return fmt.Errorf("bad long flag %q", v)
+		}
+		td.LongFlags = append(td.LongFlags, v)
+		return nil
+	}},
+	"cmd": {hasValue: true, optional: true, set: func(td *tagData, v string) error {
+		td.Command = &v
+		return nil
+	}},
+	"env": {hasValue: true, optional: true, set: func(td *tagData, v string) error {
+		td.Env = &v
+		return nil
+	}},
+	"prefix": {hasValue: true, set: func(td *tagData, v string) error {
+		td.Prefix = &v
+		return nil
+	}},
+	"required": {set: func(td *tagData, v string) error {
+		td.Required = true
+		return nil
+	}},
+	"pos": {set: func(td *tagData, v string) error {
+		td.Positional = true
+		return nil
+	}},
+	"rest": {set: func(td *tagData, v string) error {
+		td.Rest = true
+		return nil
+	}},
+	"layout": {hasValue: true, repeat: true, set: func(td *tagData, v string) error {
+		if layout, ok := layoutNames[v]; ok {
+			v = layout
+		}
+		td.Layouts = append(td.Layouts, v)
+		return nil
+	}},
+	"port": {hasValue: true, set: func(td *tagData, v string) error {
+		if _, err := strconv.ParseUint(v, 10, 16); err != nil {
+			return fmt.Errorf("bad port %q", v)
+		}
+		td.Port = v
+		return nil
+	}},
+	"family": {hasValue: true, set: func(td *tagData, v string) error {
+		if v != "ipv4" && v != "ipv6" {
+			return fmt.Errorf("family %q must be ipv4 or ipv6", v)
+		}
+		td.Family = v
+		return nil
+	}},
+	"scheme": {hasValue: true, repeat: true, set: func(td *tagData, v string) error {
+		td.Schemes = append(td.Schemes, v)
+		return nil
+	}},
+	"choices": {hasValue: true, set: func(td *tagData, v string) error {
+		td.Choices = strings.Split(v, "|")
+		for _, c := range td.Choices {
+			if c == "" {
+				return fmt.Errorf("bad choices %q", v)
+			}
+		}
+		return nil
+	}},
+	"ignorecase": {set: func(td *tagData, v string) error {
+		td.IgnoreCase = true
+		return nil
+	}},
+	"xor": {hasValue: true, set: func(td *tagData, v string) error {
+		td.Xor = v
+		return nil
+	}},
+	"oneof": {hasValue: true, set: func(td *tagData, v string) error {
+		td.OneOf = v
+		return nil
+	}},
+	"anyof": {hasValue: true, set: func(td *tagData, v string) error {
+		td.AnyOf = v
+		return nil
+	}},
+	"requires": {hasValue: true, repeat: true, set: func(td *tagData, v string) error {
+		td.Requires = append(td.Requires, v)
+		return nil
+	}},
+	"min": {hasValue: true, set: func(td *tagData, v string) error {
+		td.Min = v
+		return nil
+	}},
+	"max": {hasValue: true, set: func(td *tagData, v string) error {
+		td.Max = v
+		return nil
+	}},
+	"len": {hasValue: true, set: func(td *tagData, v string) error {
+		td.Len = v
+		return nil
+	}},
+	"pattern": {hasValue: true, set: func(td *tagData, v string) error {
+		td.Pattern = v
+		return nil
+	}},
+	"nonempty": {set: func(td *tagData, v string) error {
+		td.Nonempty = true
+		return nil
+	}},
+	"existing-file": {set: func(td *tagData, v string) error {
+		td.ExistingFile = true
+		return nil
+	}},
+	"existing-dir": {set: func(td *tagData, v string) error {
+		td.ExistingDir = true
+		return nil
+	}},
+	"si": {set: func(td *tagData, v string) error {
+		td.SI = true
+		return nil
+	}},
+	"count": {hasValue: true, optional: true, set: func(td *tagData, v string) error {
+		td.Count = true
+		if v == "" {
+			return nil
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return fmt.Errorf("bad count cap %q", v)
+		}
+		td.CountCap = n
+		return nil
+	}},
+	"nonegate": {set: func(td *tagData, v string) error {
+		td.NoNegate = true
+		return nil
+	}},
+	"implicit": {hasValue: true, set: func(td *tagData, v string) error {
+		td.Implicit = &v
+		return nil
+	}},
+	"default": {hasValue: true, set: func(td *tagData, v string) error {
+		td.Default = &v
+		return nil
+	}},
+	"config": {set: func(td *tagData, v string) error {
+		td.Config = true
+		return nil
+	}},
+}
+
+// separateTags are the keys that can be given as struct tags of their own.
+var separateTags = []string{"help", "short"}
+
+// tagParser applies keys to a tagData, making sure that each key is given
+// at most once.
+type tagParser struct {
+	td   tagData
+	seen map[string]bool
+}
+
+func (tp *tagParser) apply(key string, value string, hasValue bool) error {
+	k, ok := tagKeys[key]
+	if !ok {
+		return fmt.Errorf("unknown option %q", key)
+	}
+	if k.hasValue && !hasValue && !k.optional {
+		return fmt.Errorf("option %q needs a value", key)
+	}
+	if !k.hasValue && hasValue {
+		return fmt.Errorf("option %q does not take a value", key)
+	}
+	if tp.seen[key] && !k.repeat {
+		return fmt.Errorf("option %q is given more than once", key)
+	}
+	tp.seen[key] = true
+	return k.set(&tp.td, value)
+}
+
+func parseTagData(tag reflect.StructTag) (tagData, error) {
+	tp := &tagParser{seen: make(map[string]bool)}
+	s := tag.Get("args")
+	var err error
+	if isStructuredTag(s) {
+		err = tp.parseStructured(s)
+	} else {
+		err = tp.parseLegacy(s)
+	}
+	if err != nil {
+		return tp.td, err
+	}
+	for _, key := range separateTags {
+		if value, ok := tag.Lookup(key); ok {
+			if err := tp.apply(key, value, true); err != nil {
+				return tp.td, err
+			}
+		}
+	}
+	return tp.td, nil
+}
+
+// isStructuredTag is true if s is in the structured form. It is, if the
+// first word is a known key with a value, or if every word is a known key
+// and at least one of them has a value. A lone word like "required", or a
+// description such as "key=value pairs", is in the legacy form.
+func isStructuredTag(s string) bool {
+	s = strings.TrimLeft(s, " \t")
+	if i := strings.IndexAny(s, "= \t"); i >= 0 && s[i] == '=' {
+		if _, ok := tagKeys[s[:i]]; ok {
+			return true
+		}
+	}
+	words, err := splitTag(s)
+	if err != nil {
+		return false
+	}
+	hasValue := false
+	for _, w := range words {
+		if _, ok := tagKeys[w.key]; !ok {
+			return false
+		}
+		hasValue = hasValue || w.hasValue
+	}
+	return hasValue
+}
+
+func (tp *tagParser) parseStructured(s string) error {
+	words, err := splitTag(s)
+	if err != nil {
+		return err
+	}
+	for _, w := range words {
+		if err := tp.apply(w.key, w.value, w.hasValue); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (tp *tagParser) parseLegacy(s string) error {
+	parts := splitEscaped(s, ',')
+	if parts[0] != "" {
+		if err := tp.apply("help", parts[0], true); err != nil {
+			return err
+		}
+	}
+	for _, part := range parts[1:] {
+		var err error
+		switch {
+		case strings.HasPrefix(part, "--"):
+			err = tp.apply("long", part, true)
+		case strings.HasPrefix(part, "-"):
+			err = tp.apply("short", part, true)
+		case part == "r":
+			err = tp.apply("required", "", false)
+		default:
+			key, value, hasValue := strings.Cut(part, "=")
+			err = tp.apply(key, value, hasValue)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitEscaped splits s on sep, except where sep is escaped with a
+// backslash. Backslash escapes are removed.
+func splitEscaped(s string, sep byte) []string {
+	var parts []string
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s):
+			i++
+			b.WriteByte(s[i])
+		case s[i] == sep:
+			parts = append(parts, b.String())
+			b.Reset()
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return append(parts, b.String())
+}
+
+// tagWord is a word of the structured form.
+type tagWord struct {
+	key      string
+	value    string
+	hasValue bool
+}
+
+// splitTag splits s into the words of the structured form. If there is an
+// error, the words before the error are returned.
+func splitTag(s string) ([]tagWord, error) {
+	var words []tagWord
+	i := 0
+	for {
+		for i < len(s) && isSpace(s[i]) {
+			i++
+		}
+		if i == len(s) {
+			return words, nil
+		}
+		start := i
+		for i < len(s) && s[i] != '=' && !isSpace(s[i]) {
+			i++
+		}
+		w := tagWord{key: s[start:i]}
+		if i < len(s) && s[i] == '=' {
+			w.hasValue = true
+			value, n, err := readTagValue(s[i+1:])
+			if err != nil {
+				return words, fmt.Errorf("option %q: %s", w.key, err)
+			}
+			w.value = value
+			i += 1 + n
+		}
+		words = append(words, w)
+	}
+}
+
+// readTagValue reads a value from the front of s, and returns it along
+// with the number of bytes that were read.
+func readTagValue(s string) (string, int, error) {
+	var b strings.Builder
+	var quote byte
+	i := 0
+	if i < len(s) && (s[i] == '\'' || s[i] == '"') {
+		quote = s[i]
+		i++
+	}
+	for ; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s):
+			i++
+			b.WriteByte(s[i])
+		case quote != 0 && c == quote:
+			if i+1 < len(s) && !isSpace(s[i+1]) {
+				return "", 0, errors.New("quoted value must be followed by a space")
+			}
+			return b.String(), i + 1, nil
+		case quote == 0 && isSpace(c):
+			return b.String(), i, nil
+		default:
+			b.WriteByte(c)
+		}
+	}
+	if quote != 0 {
+		return "", 0, errors.New("unterminated quote")
+	}
+	return b.String(), i, nil
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t'
+}
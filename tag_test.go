@@ -0,0 +1,179 @@
+package args
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTagData(t *testing.T) {
+	tests := []struct {
+		tag  reflect.StructTag
+		want tagData
+	}{
+		{``, tagData{}},
+		{`args:"a foo"`, tagData{Description: "a foo"}},
+		{`args:"required"`, tagData{Description: "required"}},
+		{
+			`args:"key=value pairs to pass,-o"`,
+			tagData{Description: "key=value pairs to pass", ShortFlag: "o"},
+		},
+		{
+			`args:"a foo,-f,r"`,
+			tagData{Description: "a foo", ShortFlag: "f", Required: true},
+		},
+		{
+			`args:"a foo\\, or a bar,--foo,--bar,pos"`,
+			tagData{Description: "a foo, or a bar", LongFlags: []string{"foo", "bar"}, Positional: true},
+		},
+		{
+			`args:"a foo,short=f,required"`,
+			tagData{Description: "a foo", ShortFlag: "f", Required: true},
+		},
+		{
+			`args:"help='size, in bytes' short=s required long=size long=sz"`,
+			tagData{Description: "size, in bytes", ShortFlag: "s", Required: true, LongFlags: []string{"size", "sz"}},
+		},
+		{
+			`args:"required help=\"it's a \\\"foo\\\"\""`,
+			tagData{Description: `it's a "foo"`, Required: true},
+		},
+		{
+			`args:"help=a\\ b\\'c rest"`,
+			tagData{Description: "a b'c", Rest: true},
+		},
+		{
+			`args:"short=s" help:"size, in bytes"`,
+			tagData{Description: "size, in bytes", ShortFlag: "s"},
+		},
+		{
+			`help:"size, in bytes" short:"s"`,
+			tagData{Description: "size, in bytes", ShortFlag: "s"},
+		},
+		{
+			`args:"a name" default:"bob" env:"NAME" long:"name"`,
+			tagData{Description: "a name"},
+		},
+	}
+
+	for _, test := range tests {
+		got, err := parseTagData(test.tag)
+		if err != nil {
+			t.Errorf("%s: %s", test.tag, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %+v, want %+v", test.tag, got, test.want)
+		}
+	}
+}
+
+func TestParseTagDataErrors(t *testing.T) {
+	tests := []reflect.StructTag{
+		`args:"a foo,r,3"`,
+		`args:"a foo,-"`,
+		`args:"a foo,-ab"`,
+		`args:"a foo,--"`,
+		`args:"a foo,pos=yes"`,
+		`args:"a foo,"`,
+		`args:"help='a foo"`,
+		`args:"help='a foo'x"`,
+		`args:"help=foo shrot=f"`,
+		`args:"help=foo help=bar"`,
+		`args:"help=foo required=yes"`,
+		`args:"help=foo short"`,
+		`args:"a foo" help:"a bar"`,
+		`args:"help=foo" short:"ab"`,
+	}
+
+	for _, tag := range tests {
+		if _, err := parseTagData(tag); err == nil {
+			t.Errorf("%s: expected error", tag)
+		}
+	}
+
+	type Test struct {
+		Foo int `args:"a foo,r,3"`
+	}
+	if err := ParseArgs(&Test{}, nil); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+// TestFieldTagErrors tests tags that parse, but don't suit their fields.
+func TestFieldTagErrors(t *testing.T) {
+	tests := []struct {
+		strukt interface{}
+		err    string
+	}{
+		// Validation rules
+		{&struct {
+			A string `args:"a,min=1"`
+		}{}, `args: bad tag on field A: only numbers have a min or max`},
+		{&struct {
+			B int `args:"b,len=3"`
+		}{}, `args: bad tag on field B: only strings have a len, pattern or nonempty`},
+		{&struct {
+			C int `args:"c,max=x"`
+		}{}, `args: bad tag on field C: bad bound "x"`},
+		{&struct {
+			D string `args:"d,len=5..3"`
+		}{}, `args: bad tag on field D: bad length "5..3"`},
+		{&struct {
+			E string `args:"help=e pattern=("`
+		}{}, "args: bad tag on field E: error parsing regexp: missing closing ): `(`"},
+		{&struct {
+			F int `args:"f,existing-file"`
+		}{}, `args: bad tag on field F: only strings can name files`},
+
+		// Constraints
+		{&struct {
+			A string `args:"help=a requires=nope"`
+		}{}, `args: bad tag on field A: it requires unknown flag --nope`},
+		{&struct {
+			A string `args:"help=a xor=x"`
+			B string `args:"help=b oneof=x"`
+		}{}, `args: bad tag on field B: x is a xor set, not a oneof set`},
+		{&struct {
+			A string `args:"help=a oneof=x pos"`
+		}{}, `args: bad tag on field A: only flags can have constraints`},
+
+		// Defaults
+		{&struct {
+			A int `args:"a,default=x"`
+		}{}, `args: bad tag on field A: bad default: strconv.ParseInt: parsing "x": invalid syntax`},
+		{&struct {
+			A string `args:"help=a choices=x|y default=z"`
+		}{}, `args: bad tag on field A: bad default: option a: "z" is not x or y, did you mean x or y?`},
+
+		// Counters
+		{&struct {
+			V bool `args:"help=v count"`
+		}{}, `args: bad tag on field V: only integer flags can count`},
+		{&struct {
+			V []int `args:"help=v count"`
+		}{}, `args: bad tag on field V: only integer flags can count`},
+		{&struct {
+			V int `args:"help=v count=0"`
+		}{}, `args: bad tag on field V: bad count cap "0"`},
+
+		// Implicit values
+		{&struct {
+			V bool `args:"help=v implicit=true"`
+		}{}, `args: bad tag on field V: only flags that take one value can have an implicit value`},
+		{&struct {
+			V []string `args:"help=v implicit=x"`
+		}{}, `args: bad tag on field V: only flags that take one value can have an implicit value`},
+		{&struct {
+			V int `args:"help=v implicit=x"`
+		}{}, `args: bad tag on field V: bad implicit value: strconv.ParseInt: parsing "x": invalid syntax`},
+		{&struct {
+			V string `args:"help=v choices=a|b implicit=c"`
+		}{}, `args: bad tag on field V: bad implicit value: option v: "c" is not a or b, did you mean a or b?`},
+	}
+	for _, test := range tests {
+		err := ParseArgs(test.strukt, nil)
+		if err == nil || err.Error() != test.err {
+			t.Errorf("%T: got error %v, want %s", test.strukt, err, test.err)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package args
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	timeType     = reflect.TypeOf(time.Time{})
+)
+
+// defaultLayouts are the layouts that times are parsed with, unless their
+// field's tag gives others.
+var defaultLayouts = []string{time.RFC3339, time.DateOnly}
+
+// layoutNames are the names of layouts that the layout tag key accepts, as
+// well as the layouts themselves.
+var layoutNames = map[string]string{
+	"ANSIC":       time.ANSIC,
+	"UnixDate":    time.UnixDate,
+	"RubyDate":    time.RubyDate,
+	"RFC822":      time.RFC822,
+	"RFC822Z":     time.RFC822Z,
+	"RFC850":      time.RFC850,
+	"RFC1123":     time.RFC1123,
+	"RFC1123Z":    time.RFC1123Z,
+	"RFC3339":     time.RFC3339,
+	"RFC3339Nano": time.RFC3339Nano,
+	"Kitchen":     time.Kitchen,
+	"DateTime":    time.DateTime,
+	"DateOnly":    time.DateOnly,
+	"TimeOnly":    time.TimeOnly,
+}
+
+// isTime is true if t is a time.Time, or a pointer to or slice of them.
+func isTime(t reflect.Type) bool {
+	return baseType(t) == timeType
+}
+
+// temporal is true if the option holds times or durations, which can be
+// negative, as in -2h.
+func (opt *option) temporal() bool {
+	t := baseType(opt.field.Type)
+	return t == timeType || t == durationType
+}
+
+// setTimes parses data as times, and assigns them to opt.
+func (opt *option) setTimes(data []string, now time.Time) error {
+	v := opt.value
+	if v.Kind() == reflect.Ptr {
+		v.Set(reflect.New(v.Type().Elem()))
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Slice {
+		if err := checkArgLen(data, opt.name); err != nil {
+			return err
+		}
+	}
+	times := reflect.MakeSlice(reflect.SliceOf(timeType), len(data), len(data))
+	for i, s := range data {
+		t, err := parseTime(s, opt.tag.Layouts, now)
+		if err != nil {
+			return fmt.Errorf("args: option %s: %s", opt.name, err)
+		}
+		times.Index(i).Set(reflect.ValueOf(t))
+	}
+	if v.Kind() != reflect.Slice {
+		v.Set(times.Index(0))
+		return nil
+	}
+	slice := reflect.MakeSlice(v.Type(), len(data), len(data))
+	for i := range data {
+		elem := slice.Index(i)
+		if elem.Kind() == reflect.Ptr {
+			elem.Set(reflect.New(timeType))
+			elem = elem.Elem()
+		}
+		elem.Set(times.Index(i))
+	}
+	v.Set(slice)
+	return nil
+}
+
+// parseTime parses s with the first of layouts that fits it, or with the
+// default layouts if there are none. It also understands now, today,
+// yesterday and tomorrow, and durations relative to now, such as -2h or
+// +30m. Times without a zone are in the location of now.
+func parseTime(s string, layouts []string, now time.Time) (time.Time, error) {
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch strings.ToLower(s) {
+	case "now":
+		return now, nil
+	case "today":
+		return midnight, nil
+	case "yesterday":
+		return midnight.AddDate(0, 0, -1), nil
+	case "tomorrow":
+		return midnight.AddDate(0, 0, 1), nil
+	}
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+		if d, err := time.ParseDuration(s); err == nil {
+			return now.Add(d), nil
+		}
+	}
+	if len(layouts) == 0 {
+		layouts = defaultLayouts
+	}
+	for _, layout := range layouts {
+		if t, err := time.ParseInLocation(layout, s, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf(
+		"can't parse %q as a time in the layout %s, or relative to now as in -2h or yesterday",
+		s, orList(layouts))
+}
+
+// isNegativeDuration is true if arg is a negative duration, such as -2h.
+func isNegativeDuration(arg string) bool {
+	if len(arg) < 2 || arg[0] != '-' || !(isDigit(arg[1]) || arg[1] == '.') {
+		return false
+	}
+	_, err := time.ParseDuration(arg)
+	return err == nil
+}
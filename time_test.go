@@ -0,0 +1,107 @@
+package args
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDuration(t *testing.T) {
+	type Test struct {
+		Timeout  time.Duration   `args:"timeout,-t"`
+		Offset   time.Duration   `args:"offset"`
+		Retry    *time.Duration  `args:"retry delay"`
+		Backoffs []time.Duration `args:"backoff delays"`
+	}
+	var got Test
+	testArgs := []string{"-t", "5s", "--offset", "-1m30s", "--retry", "250ms", "--backoffs", "1s", "2s"}
+	if err := ParseArgs(&got, testArgs); err != nil {
+		t.Fatal(err)
+	}
+	retry := 250 * time.Millisecond
+	want := Test{
+		Timeout:  5 * time.Second,
+		Offset:   -90 * time.Second,
+		Retry:    &retry,
+		Backoffs: []time.Duration{time.Second, 2 * time.Second},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("bad data: got %+v, want %+v", got, want)
+	}
+	if err := ParseArgs(&got, []string{"--timeout", "5"}); err == nil {
+		t.Fatal("expected error")
+	}
+
+	var buf bytes.Buffer
+	if err := Usage(&buf, Test{Timeout: 90 * time.Second}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "--timeout\t(default: 1m30s)"; !strings.Contains(buf.String(), want) {
+		t.Fatalf("usage is missing %q:\n%s", want, buf.String())
+	}
+}
+
+func TestTime(t *testing.T) {
+	type Test struct {
+		Since  time.Time   `args:"start time"`
+		Until  *time.Time  `args:"end time"`
+		Day    time.Time   `args:"a day"`
+		At     time.Time   `args:"help='wall clock' layout=Kitchen layout='15:04'"`
+		Events []time.Time `args:"event times"`
+	}
+	now := time.Date(2024, 3, 10, 15, 30, 0, 0, time.UTC)
+	p := &Parser{Now: func() time.Time { return now }}
+
+	var got Test
+	testArgs := []string{
+		"--since", "-2h", "--until", "2024-03-10T12:00:00Z", "--day", "yesterday",
+		"--at", "3:04PM", "--events", "2024-01-02", "today", "+30m",
+	}
+	if err := p.ParseArgs(&got, testArgs); err != nil {
+		t.Fatal(err)
+	}
+	until := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	want := Test{
+		Since: now.Add(-2 * time.Hour),
+		Until: &until,
+		Day:   time.Date(2024, 3, 9, 0, 0, 0, 0, time.UTC),
+		At:    time.Date(0, 1, 1, 15, 4, 0, 0, time.UTC),
+		Events: []time.Time{
+			time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+			time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC),
+			now.Add(30 * time.Minute),
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("bad data: got %+v, want %+v", got, want)
+	}
+
+	for _, args := range [][]string{
+		{"--since", "last week"},
+		{"--at", "2024-03-10"},
+	} {
+		if err := p.ParseArgs(&Test{}, args); err == nil {
+			t.Fatalf("%q: expected error", args)
+		}
+	}
+
+	type BadLayout struct {
+		Name string `args:"a name,layout=Kitchen"`
+	}
+	if err := p.ParseArgs(&BadLayout{}, nil); err == nil {
+		t.Fatal("expected error")
+	}
+
+	var buf bytes.Buffer
+	p.Stdout = &buf
+	if err := p.Usage(Test{Since: until}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"--since\t(default: 2024-03-10T12:00:00Z)", "--day\t(default: none)"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Fatalf("usage is missing %q:\n%s", want, buf.String())
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package args
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ByteSize is a number of bytes. It is parsed with an optional SI or IEC
+// suffix, as in 100, 512MiB, 1.5GB or 64k. SI suffixes (k, M, G, T, P, E,
+// with or without B) are powers of 1000, and IEC suffixes (KiB, MiB, GiB,
+// TiB, PiB, EiB) are powers of 1024.
+type ByteSize uint64
+
+// UnmarshalArg implements Unmarshaler.
+func (b *ByteSize) UnmarshalArg(arg string) error {
+	s := strings.TrimSuffix(arg, "B")
+	base := uint64(1000)
+	if strings.HasSuffix(s, "i") {
+		// An IEC suffix must have a prefix: KiB, not iB.
+		s, base = s[:len(s)-1], 1024
+		if s == "" || prefixPower(s[len(s)-1:]) == 0 {
+			return fmt.Errorf("bad byte size %q", arg)
+		}
+	}
+	n, err := parseScaled(s, base)
+	if err != nil {
+		return fmt.Errorf("bad byte size %q", arg)
+	}
+	*b = ByteSize(n)
+	return nil
+}
+
+// String formats b with the largest suffix that divides it, so that it
+// parses back to the same size.
+func (b ByteSize) String() string {
+	n := uint64(b)
+	for _, base := range []uint64{1024, 1000} {
+		for i := len(siPrefixes); i > 0; i-- {
+			mult := pow(base, i)
+			if n != 0 && n%mult == 0 {
+				suffix := siPrefixes[i-1:i] + "B"
+				if base == 1024 {
+					suffix = strings.ToUpper(siPrefixes[i-1:i]) + "iB"
+				}
+				return strconv.FormatUint(n/mult, 10) + suffix
+			}
+		}
+	}
+	return strconv.FormatUint(n, 10) + "B"
+}
+
+// Percent is a fraction, such as 0.75. It is parsed as a percentage, as in
+// 75%, or as a fraction.
+type Percent float64
+
+// UnmarshalArg implements Unmarshaler.
+func (p *Percent) UnmarshalArg(arg string) error {
+	s := strings.TrimSuffix(arg, "%")
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return fmt.Errorf("bad percentage %q", arg)
+	}
+	if s != arg {
+		f /= 100
+	}
+	*p = Percent(f)
+	return nil
+}
+
+// String formats p as a percentage.
+func (p Percent) String() string {
+	return strconv.FormatFloat(float64(p)*100, 'g', 10, 64) + "%"
+}
+
+// isInteger is true if t holds integers.
+func isInteger(t reflect.Type) bool {
+	t = baseType(t)
+	if isValue(t) {
+		return false
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// siPrefixes are the SI prefixes for powers of 1000, in order.
+const siPrefixes = "kMGTPE"
+
+// parseSI parses s, which is an integer with an optional SI suffix, as in
+// 10k or 1.5M, and returns it without the suffix. Integers without a
+// suffix are returned as they are.
+func parseSI(s string) (string, error) {
+	if s == "" || prefixPower(s[len(s)-1:]) == 0 {
+		return s, nil
+	}
+	neg := strings.HasPrefix(s, "-")
+	n, err := parseScaled(strings.TrimPrefix(s, "-"), 1000)
+	if err != nil {
+		return "", fmt.Errorf("bad number %q", s)
+	}
+	if neg {
+		return "-" + strconv.FormatUint(n, 10), nil
+	}
+	return strconv.FormatUint(n, 10), nil
+}
+
+// parseScaled parses s, which is a number with an optional SI prefix that
+// scales it by a power of base. The result must be a whole number.
+func parseScaled(s string, base uint64) (uint64, error) {
+	mult := uint64(1)
+	if s != "" {
+		if n := prefixPower(s[len(s)-1:]); n > 0 {
+			s, mult = s[:len(s)-1], pow(base, n)
+		}
+	}
+	if n, err := strconv.ParseUint(s, 10, 64); err == nil {
+		if n > math.MaxUint64/mult {
+			return 0, strconv.ErrRange
+		}
+		return n * mult, nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || f < 0 {
+		return 0, strconv.ErrSyntax
+	}
+	f *= float64(mult)
+	if f != math.Trunc(f) || f >= math.MaxUint64 {
+		return 0, strconv.ErrRange
+	}
+	return uint64(f), nil
+}
+
+// prefixPower returns the power of the SI prefix p, which is 1 for k, or 0
+// if p isn't a prefix. K is accepted for k.
+func prefixPower(p string) int {
+	if p == "K" {
+		p = "k"
+	}
+	return strings.Index(siPrefixes, p) + 1
+}
+
+func pow(base uint64, n int) uint64 {
+	result := uint64(1)
+	for i := 0; i < n; i++ {
+		result *= base
+	}
+	return result
+}
@@ -0,0 +1,110 @@
+package args
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestByteSize(t *testing.T) {
+	tests := []struct {
+		arg  string
+		size ByteSize
+	}{
+		{"100", 100},
+		{"100B", 100},
+		{"64k", 64000},
+		{"64K", 64000},
+		{"64kB", 64000},
+		{"512MiB", 512 << 20},
+		{"1.5GB", 1500000000},
+		{"1.5KiB", 1536},
+		{"2EiB", 2 << 60},
+	}
+	for _, test := range tests {
+		var b ByteSize
+		if err := b.UnmarshalArg(test.arg); err != nil {
+			t.Fatalf("%s: %s", test.arg, err)
+		}
+		if b != test.size {
+			t.Fatalf("%s: got %d, want %d", test.arg, b, test.size)
+		}
+		// String must round-trip.
+		var again ByteSize
+		if err := again.UnmarshalArg(b.String()); err != nil || again != b {
+			t.Fatalf("%s: %s doesn't round-trip", test.arg, b)
+		}
+	}
+	for _, arg := range []string{"", "MiB", "5iB", "1.5B", "-1k", "20EiB", "5XB"} {
+		var b ByteSize
+		if err := b.UnmarshalArg(arg); err == nil {
+			t.Fatalf("%q: expected error", arg)
+		}
+	}
+	if s := ByteSize(512 << 20).String(); s != "512MiB" {
+		t.Fatalf("bad string: %s", s)
+	}
+	if s := ByteSize(3000000).String(); s != "3MB" {
+		t.Fatalf("bad string: %s", s)
+	}
+}
+
+func TestUnits(t *testing.T) {
+	type Test struct {
+		Cache   ByteSize   `args:"cache size"`
+		Limits  []ByteSize `args:"limits"`
+		Rate    int        `args:"rate,si"`
+		Counts  []uint32   `args:"counts,si"`
+		Plain   int        `args:"plain"`
+		Ratio   Percent    `args:"ratio"`
+		Weights []Percent  `args:"weights"`
+	}
+	var got Test
+	testArgs := []string{
+		"--cache", "512MiB", "--limits", "1k", "2KiB", "--rate", "10k",
+		"--counts", "1.5M", "0x10", "--ratio", "75%", "--weights", "0.25", "7%",
+	}
+	if err := ParseArgs(&got, testArgs); err != nil {
+		t.Fatal(err)
+	}
+	want := Test{
+		Cache:   512 << 20,
+		Limits:  []ByteSize{1000, 2048},
+		Rate:    10000,
+		Counts:  []uint32{1500000, 16},
+		Ratio:   0.75,
+		Weights: []Percent{0.25, 0.07},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("bad data: got %+v, want %+v", got, want)
+	}
+
+	for _, args := range [][]string{
+		{"--plain", "10k"},
+		{"--rate", "1.5"},
+		{"--counts", "5G"},
+		{"--ratio", "most"},
+	} {
+		if err := ParseArgs(&Test{}, args); err == nil {
+			t.Fatalf("%q: expected error", args)
+		}
+	}
+
+	type BadSI struct {
+		Ratio float64 `args:"ratio,si"`
+	}
+	if err := ParseArgs(&BadSI{}, nil); err == nil {
+		t.Fatal("expected error")
+	}
+
+	var buf bytes.Buffer
+	if err := Usage(&buf, Test{Cache: 64 << 20, Ratio: 0.07}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"--cache\t(default: 64MiB)", "--ratio\t(default: 7%)"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Fatalf("usage is missing %q:\n%s", want, buf.String())
+		}
+	}
+}
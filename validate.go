@@ -0,0 +1,226 @@
+package args
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ValidationError is returned when values break the validation rules in
+// the tags of their fields, don't convert to their fields, or are missing
+// although they are required. It holds every violation, rather than just
+// the first.
+type ValidationError struct {
+	Violations []error
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for i, err := range e.Violations {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Unwrap returns the violations.
+func (e *ValidationError) Unwrap() []error {
+	return e.Violations
+}
+
+// rules are the validation rules of an option, parsed from its tag.
+type rules struct {
+	min, max       reflect.Value // the zero Value if there is no bound
+	minLen, maxLen int           // -1 if there is no bound
+	pattern        *regexp.Regexp
+}
+
+// parseRules parses the validation rules in the tag of opt, making sure
+// that they suit its type.
+func (opt *option) parseRules() error {
+	td := opt.tag
+	opt.rules = rules{minLen: -1, maxLen: -1}
+	t := baseType(opt.field.Type)
+	bad := func(format string, a ...interface{}) error {
+		return fmt.Errorf("args: bad tag on field %s: %s", opt.path, fmt.Sprintf(format, a...))
+	}
+	for _, bound := range []struct {
+		s string
+		v *reflect.Value
+	}{{td.Min, &opt.rules.min}, {td.Max, &opt.rules.max}} {
+		if bound.s == "" {
+			continue
+		}
+		if !isOrdered(t) {
+			return bad("only numbers have a min or max")
+		}
+		v := reflect.New(t).Elem()
+		if err := setField(v, opt.name, []string{bound.s}); err != nil {
+			return bad("bad bound %q", bound.s)
+		}
+		*bound.v = v
+	}
+	if td.Len != "" || td.Pattern != "" || td.Nonempty {
+		if t.Kind() != reflect.String || isValue(t) {
+			return bad("only strings have a len, pattern or nonempty")
+		}
+	}
+	if td.Len != "" {
+		var err error
+		if opt.rules.minLen, opt.rules.maxLen, err = parseLen(td.Len); err != nil {
+			return bad("%s", err)
+		}
+	}
+	if td.Pattern != "" {
+		// Compile the pattern alone first, so that errors show it as it
+		// was written.
+		if _, err := regexp.Compile(td.Pattern); err != nil {
+			return bad("%s", err)
+		}
+		opt.rules.pattern = regexp.MustCompile("^(?:" + td.Pattern + ")$")
+	}
+	if (td.ExistingFile || td.ExistingDir) && (t.Kind() != reflect.String || isValue(t)) {
+		return bad("only strings can name files")
+	}
+	return nil
+}
+
+// parseLen parses a length or a range of lengths, such as 3, 1..64, ..64
+// or 8.., and returns the bounds, which are -1 if they are open.
+func parseLen(s string) (int, int, error) {
+	lo, hi := s, s
+	if i := strings.Index(s, ".."); i >= 0 {
+		lo, hi = s[:i], s[i+2:]
+	}
+	bounds := []int{-1, -1}
+	for i, b := range []string{lo, hi} {
+		if b == "" {
+			continue
+		}
+		n, err := strconv.Atoi(b)
+		if err != nil || n < 0 {
+			return 0, 0, fmt.Errorf("bad length %q", s)
+		}
+		bounds[i] = n
+	}
+	if (bounds[0] < 0 && bounds[1] < 0) || (bounds[1] >= 0 && bounds[0] > bounds[1]) {
+		return 0, 0, fmt.Errorf("bad length %q", s)
+	}
+	return bounds[0], bounds[1], nil
+}
+
+// isOrdered is true if values of t can be compared with min and max.
+func isOrdered(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// compare returns -1, 0 or 1 as a is less than, equal to or greater than
+// b, which are of the same ordered type.
+func compare(a, b reflect.Value) int {
+	var less, greater bool
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		less, greater = a.Int() < b.Int(), a.Int() > b.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		less, greater = a.Uint() < b.Uint(), a.Uint() > b.Uint()
+	default:
+		less, greater = a.Float() < b.Float(), a.Float() > b.Float()
+	}
+	switch {
+	case less:
+		return -1
+	case greater:
+		return 1
+	}
+	return 0
+}
+
+// validate checks the value of opt against its validation rules, and
+// returns every violation. The rules apply to each element of a slice.
+func (p *Parser) validate(opt *option) []error {
+	td := opt.tag
+	var errs []error
+	fail := func(format string, a ...interface{}) {
+		errs = append(errs, fmt.Errorf("args: option %s: %s", opt.name, fmt.Sprintf(format, a...)))
+	}
+	v := reflect.Indirect(opt.value)
+	if !v.IsValid() {
+		return nil
+	}
+	values := []reflect.Value{v}
+	if v.Kind() == reflect.Slice && !isValue(v.Type()) {
+		if td.Nonempty && v.Len() == 0 {
+			fail("no values were given")
+		}
+		values = values[:0]
+		for i := 0; i < v.Len(); i++ {
+			if elem := reflect.Indirect(v.Index(i)); elem.IsValid() {
+				values = append(values, elem)
+			}
+		}
+	}
+	r := opt.rules
+	for _, v := range values {
+		if r.min.IsValid() && compare(v, r.min) < 0 {
+			fail("%s is less than the minimum of %s", formatValue(v), formatValue(r.min))
+		}
+		if r.max.IsValid() && compare(v, r.max) > 0 {
+			fail("%s is more than the maximum of %s", formatValue(v), formatValue(r.max))
+		}
+		if v.Kind() != reflect.String {
+			continue
+		}
+		s := v.String()
+		if td.Nonempty && s == "" {
+			fail("the value is empty")
+		}
+		if n := utf8.RuneCountInString(s); r.minLen >= 0 && n < r.minLen {
+			fail("%q is shorter than %d characters", s, r.minLen)
+		} else if r.maxLen >= 0 && n > r.maxLen {
+			fail("%q is longer than %d characters", s, r.maxLen)
+		}
+		if r.pattern != nil && !r.pattern.MatchString(s) {
+			fail("%q doesn't match the pattern %s", s, td.Pattern)
+		}
+		if td.ExistingFile || td.ExistingDir {
+			if err := p.checkExists(s, td.ExistingDir); err != nil {
+				fail("%s", err)
+			}
+		}
+	}
+	return errs
+}
+
+// checkExists returns an error unless name is an existing directory, if
+// dir is true, or an existing file that isn't a directory.
+func (p *Parser) checkExists(name string, dir bool) error {
+	var info fs.FileInfo
+	var err error
+	if p.FS != nil {
+		info, err = fs.Stat(p.FS, name)
+	} else {
+		info, err = os.Stat(name)
+	}
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return fmt.Errorf("%q does not exist", name)
+	case err != nil:
+		return err
+	case dir && !info.IsDir():
+		return fmt.Errorf("%q is not a directory", name)
+	case !dir && info.IsDir():
+		return fmt.Errorf("%q is a directory", name)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package args
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+type ValidateTest struct {
+	Port    int           `args:"a port,min=1,max=65535"`
+	Ratio   float64       `args:"help='a ratio' min=0 max=1"`
+	Timeout time.Duration `args:"help='a timeout' min=1s max=1m"`
+	Cache   ByteSize      `args:"help='cache size' max=1GiB"`
+	Sizes   []uint        `args:"sizes,max=10"`
+	Name    string        `args:"help='a name' nonempty len=..8"`
+	Code    string        `args:"help='a code' len=3"`
+	Tags    []string      `args:"help='tags' nonempty pattern='[a-z]+'"`
+	Config  string        `args:"config file,existing-file"`
+	Dir     *string       `args:"a directory,existing-dir"`
+	Skipped int           `args:"not given,min=10"`
+}
+
+func TestValidate(t *testing.T) {
+	p := &Parser{FS: fstest.MapFS{"etc/app.ini": {Data: []byte("")}}}
+	good := []string{
+		"--port", "80", "--ratio", "0.5", "--timeout", "30s", "--cache", "512MiB",
+		"--sizes", "1", "10", "--name", "x", "--code", "abc", "--tags", "a", "bc",
+		"--config", "etc/app.ini", "--dir", "etc",
+	}
+	if err := p.ParseArgs(&ValidateTest{}, good); err != nil {
+		t.Fatal(err)
+	}
+
+	bad := []string{
+		"--port", "0", "--ratio", "1.5", "--timeout", "2m", "--cache", "2GiB",
+		"--sizes", "1", "11", "--name", "", "--code", "ab", "--tags", "a", "B",
+		"--config", "etc", "--dir", "etc/app.ini",
+	}
+	err := p.ParseArgs(&ValidateTest{}, bad)
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+	want := []string{
+		"args: option port: 0 is less than the minimum of 1",
+		"args: option ratio: 1.5 is more than the maximum of 1",
+		"args: option timeout: 2m0s is more than the maximum of 1m0s",
+		"args: option cache: 2GiB is more than the maximum of 1GiB",
+		"args: option sizes: 11 is more than the maximum of 10",
+		"args: option name: the value is empty",
+		`args: option code: "ab" is shorter than 3 characters`,
+		`args: option tags: "B" doesn't match the pattern [a-z]+`,
+		`args: option config: "etc" is a directory`,
+		`args: option dir: "etc/app.ini" is not a directory`,
+	}
+	if got := err.Error(); got != strings.Join(want, "\n") {
+		t.Fatalf("bad error: got\n%s\nwant\n%s", got, strings.Join(want, "\n"))
+	}
+
+	for _, args := range [][]string{
+		{"--name", "much too long"},
+		{"--config", "nope.ini"},
+		{"--tags"},
+	} {
+		if err := p.ParseArgs(&ValidateTest{}, args); err == nil {
+			t.Fatalf("%q: expected error", args)
+		}
+	}
+
+	// Values that don't convert and missing arguments are reported along
+	// with the violations.
+	type Mixed struct {
+		Port  int    `args:"a port,min=1"`
+		Size  int    `args:"a size"`
+		Name  string `args:"a name,r"`
+		Input string `args:"an input,pos"`
+	}
+	err = ParseArgs(&Mixed{}, []string{"--port", "0", "--size", "big"})
+	if !errors.As(err, &verr) || len(verr.Violations) != 4 {
+		t.Fatalf("expected four violations, got %v", err)
+	}
+	for i, want := range []string{
+		`invalid syntax`,
+		`required argument was not supplied: --name`,
+		`required argument was not supplied: INPUT`,
+		`args: option port: 0 is less than the minimum of 1`,
+	} {
+		if !strings.Contains(verr.Violations[i].Error(), want) {
+			t.Fatalf("violation %d: got %s, want %s", i, verr.Violations[i], want)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package args
+
+import (
+	"encoding"
+	"flag"
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"time"
+)
+
+// Unmarshaler is implemented by types that parse their own values from
+// arguments. It is used in preference to flag.Value and
+// encoding.TextUnmarshaler, which are also supported.
+type Unmarshaler interface {
+	UnmarshalArg(arg string) error
+}
+
+var (
+	unmarshalerType     = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
+	flagValueType       = reflect.TypeOf((*flag.Value)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// builtins parse the values of types that don't parse their own.
+var builtins = map[reflect.Type]func(s string) (interface{}, error){
+	durationType: func(s string) (interface{}, error) {
+		return time.ParseDuration(s)
+	},
+	urlType: func(s string) (interface{}, error) {
+		u, err := url.Parse(s)
+		if err != nil {
+			return nil, err
+		}
+		return *u, nil
+	},
+	ipNetType: func(s string) (interface{}, error) {
+		_, n, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, err
+		}
+		return *n, nil
+	},
+	hostPortType: func(s string) (interface{}, error) {
+		return parseHostPort(s)
+	},
+}
+
+// isValue is true if t, or the type that t points to, holds a single
+// value that is parsed by its own methods or by one of the builtins.
+func isValue(t reflect.Type) bool {
+	return isCustom(t) || builtins[indirect(t)] != nil
+}
+
+// isCustom is true if t, or the type that t points to, parses its own
+// values, with either a value or a pointer receiver.
+func isCustom(t reflect.Type) bool {
+	t = indirect(t)
+	for _, it := range []reflect.Type{unmarshalerType, flagValueType, textUnmarshalerType} {
+		if t.Implements(it) || reflect.PtrTo(t).Implements(it) {
+			return true
+		}
+	}
+	return false
+}
+
+// kindOf returns the kind of value that a field of type t holds, looking
+// through pointers. Types that parse their own values, and the builtins,
+// hold single values, like strings, whatever their underlying kind.
+func kindOf(t reflect.Type) reflect.Kind {
+	if isValue(t) {
+		return reflect.String
+	}
+	return indirect(t).Kind()
+}
+
+// setCustom sets v, which must be addressable, from data if its type
+// parses its own values. It returns false if it doesn't. A flag.Value is
+// set once for each value, so it can collect values itself; the other
+// interfaces take exactly one.
+func setCustom(v reflect.Value, name string, data []string) (bool, error) {
+	var err error
+	switch u := v.Addr().Interface().(type) {
+	case Unmarshaler:
+		if err := checkArgLen(data, name); err != nil {
+			return true, err
+		}
+		err = u.UnmarshalArg(data[0])
+	case flag.Value:
+		if len(data) == 0 {
+			return true, checkArgLen(data, name)
+		}
+		for _, d := range data {
+			if err = u.Set(d); err != nil {
+				break
+			}
+		}
+	case encoding.TextUnmarshaler:
+		if err := checkArgLen(data, name); err != nil {
+			return true, err
+		}
+		err = u.UnmarshalText([]byte(data[0]))
+	default:
+		return false, nil
+	}
+	if err != nil {
+		return true, fmt.Errorf("args: option %s: %s", name, err)
+	}
+	return true, nil
+}
+
+// formatValue returns the text of v for Usage. Values are formatted with
+// their String or MarshalText methods if they have them, and strings are
+// quoted. Times are formatted in RFC 3339, and empty structs and IPs are
+// shown as none.
+func formatValue(v reflect.Value) string {
+	if (v.Kind() == reflect.Struct || v.Type() == ipType) && v.IsZero() {
+		return "none"
+	}
+	if t, ok := v.Interface().(time.Time); ok {
+		return t.Format(time.RFC3339)
+	}
+	i := v.Interface()
+	if v.CanAddr() {
+		i = v.Addr().Interface()
+	}
+	switch m := i.(type) {
+	case fmt.Stringer:
+		return m.String()
+	case encoding.TextMarshaler:
+		if text, err := m.MarshalText(); err == nil {
+			return string(text)
+		}
+	}
+	if v.Kind() == reflect.String {
+		return fmt.Sprintf("%q", v.Interface())
+	}
+	return fmt.Sprintf("%v", v.Interface())
+}
@@ -0,0 +1,134 @@
+package args
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// logLevel is a TextUnmarshaler and TextMarshaler.
+type logLevel int
+
+var levelNames = []string{"debug", "info", "warn", "error"}
+
+func (l *logLevel) UnmarshalText(text []byte) error {
+	for i, name := range levelNames {
+		if name == string(text) {
+			*l = logLevel(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown level %q", text)
+}
+
+func (l logLevel) MarshalText() ([]byte, error) {
+	return []byte(levelNames[l]), nil
+}
+
+// region is an Unmarshaler.
+type region string
+
+func (r *region) UnmarshalArg(arg string) error {
+	if len(arg) != 2 {
+		return errors.New("regions have two letters")
+	}
+	*r = region(strings.ToUpper(arg))
+	return nil
+}
+
+// version is a flag.Value.
+type version struct {
+	Major, Minor int
+}
+
+func (v *version) Set(s string) error {
+	_, err := fmt.Sscanf(s, "v%d.%d", &v.Major, &v.Minor)
+	return err
+}
+
+func (v *version) String() string {
+	return fmt.Sprintf("v%d.%d", v.Major, v.Minor)
+}
+
+// labels is a flag.Value that collects its values.
+type labels []string
+
+func (l *labels) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
+func (l *labels) String() string {
+	return strings.Join(*l, ",")
+}
+
+func TestCustomValues(t *testing.T) {
+	type Test struct {
+		Level    logLevel   `args:"log level"`
+		MaxLevel *logLevel  `args:"max log level"`
+		Region   region     `args:"region,-r"`
+		Regions  []region   `args:"regions"`
+		Version  version    `args:"version"`
+		Versions []*version `args:"versions"`
+		Labels   labels     `args:"labels,-l"`
+	}
+	var got Test
+	testArgs := []string{
+		"--level", "warn", "--max-level", "error", "-r", "us",
+		"--regions", "eu", "ap", "--version", "v1.2", "--versions", "v1.0", "v2.0",
+		"-l", "a", "-l", "b",
+	}
+	if err := ParseArgs(&got, testArgs); err != nil {
+		t.Fatal(err)
+	}
+	maxLevel := logLevel(3)
+	want := Test{
+		Level:    2,
+		MaxLevel: &maxLevel,
+		Region:   "US",
+		Regions:  []region{"EU", "AP"},
+		Version:  version{1, 2},
+		Versions: []*version{{1, 0}, {2, 0}},
+		Labels:   labels{"a", "b"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("bad data: got %+v, want %+v", got, want)
+	}
+
+	for _, args := range [][]string{
+		{"--level", "loud"},
+		{"--regions", "eu", "asia"},
+		{"--level", "info", "--level", "warn"},
+	} {
+		if err := ParseArgs(&Test{}, args); err == nil {
+			t.Fatalf("%q: expected error", args)
+		}
+	}
+
+	m := make(map[string]logLevel)
+	if err := ParseArgs(&m, []string{"--net", "debug", "--db", "error"}); err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]logLevel{"net": 0, "db": 3}; !reflect.DeepEqual(m, want) {
+		t.Fatalf("bad data: got %v, want %v", m, want)
+	}
+}
+
+func TestCustomUsage(t *testing.T) {
+	type Test struct {
+		Level   logLevel `args:"log level"`
+		Version version  `args:"version"`
+	}
+	var buf bytes.Buffer
+	if err := Usage(&buf, Test{Level: 1, Version: version{1, 2}}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"--level\t(default: info)", "--version\t(default: v1.2)"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Fatalf("usage is missing %q:\n%s", want, buf.String())
+		}
+	}
+}